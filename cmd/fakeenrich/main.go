@@ -0,0 +1,102 @@
+// Command fakeenrich запускает имитацию API agify, genderize и nationalize
+// (см. пакет fakeenrich), чтобы сервис можно было запускать без доступа в
+// интернет и воспроизводить сбои внешних API:
+//
+//	go run ./cmd/fakeenrich -addr :8081 -latency 50ms -error-rate 0.1 -rate-limit 100
+//
+// и в .env:
+//
+//	AGE_API_URL=http://localhost:8081/age
+//	GENDER_API_URL=http://localhost:8081/gender
+//	NATIONALITY_API_URL=http://localhost:8081/nationality
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/k1lls3x/person-service/internal/dataset"
+	"github.com/k1lls3x/person-service/internal/fakeenrich"
+)
+
+func main() {
+	var (
+		addr        = flag.String("addr", ":8081", "listen address")
+		datasetPath = flag.String("dataset", "", "CSV or JSON name dataset (default: built-in sample)")
+		scriptPath  = flag.String("script", "", `JSON file with scripted responses: {"age": [{"status": 429, "headers": {"Retry-After": "1"}}], ...}`)
+		cfg         fakeenrich.Config
+	)
+	flag.DurationVar(&cfg.Latency, "latency", 0, "delay before every response")
+	flag.DurationVar(&cfg.Jitter, "jitter", 0, "random extra delay up to this value")
+	flag.Float64Var(&cfg.ErrorRate, "error-rate", 0, "share of requests (0..1) answered with 500")
+	flag.IntVar(&cfg.RateLimit, "rate-limit", 0, "requests per API per rate window before 429, 0 disables the limit")
+	flag.DurationVar(&cfg.RateWindow, "rate-window", time.Second, "rate limit window")
+	flag.Int64Var(&cfg.Seed, "seed", 1, "seed for random errors and jitter")
+	flag.Parse()
+
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout})
+
+	var data *dataset.Dataset
+	if *datasetPath != "" {
+		var err error
+		if data, err = dataset.Load(*datasetPath); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load dataset")
+		}
+	}
+	fake := fakeenrich.New(cfg, data)
+
+	if *scriptPath != "" {
+		scripts, err := loadScripts(*scriptPath)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", *scriptPath).Msg("Failed to load scripted responses")
+		}
+		for api, responses := range scripts {
+			fake.Script(api, responses...)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: *addr, Handler: fake.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Info().
+		Str("addr", *addr).
+		Dur("latency", cfg.Latency).
+		Float64("error_rate", cfg.ErrorRate).
+		Int("rate_limit", cfg.RateLimit).
+		Msg("Fake enrichment APIs listening")
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal().Err(err).Msg("Server failed")
+	}
+}
+
+func loadScripts(path string) (map[string][]fakeenrich.Response, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var scripts map[string][]fakeenrich.Response
+	if err := json.NewDecoder(f).Decode(&scripts); err != nil {
+		return nil, err
+	}
+	return scripts, nil
+}
@@ -3,16 +3,29 @@
 // @description REST API для сервиса обогащения ФИО возрастом, полом и национальностью
 // @host localhost:8888
 // @BasePath /
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description Bearer-токен из ADMIN_TOKEN: "Bearer <token>".
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
+	"github.com/k1lls3x/person-service/internal/cache"
 	"github.com/k1lls3x/person-service/internal/client"
+	"github.com/k1lls3x/person-service/internal/dataset"
+	"github.com/k1lls3x/person-service/internal/entity"
 	"github.com/k1lls3x/person-service/internal/handler"
 	"github.com/k1lls3x/person-service/internal/repository"
 	"github.com/k1lls3x/person-service/internal/service"
@@ -49,18 +62,134 @@ func main() {
 	log.Info().Msg("Подключение к PostgreSQL успешно")
 
 	apiClient := client.NewAPIClient(cfg.AgeAPIURL, cfg.GenderAPIURL, cfg.NationalityAPIURL)
-	personService := service.NewPersonService(db, apiClient)
-	h := handler.NewHandler(personService)
+	apiClient.Retry = map[string]client.RetryPolicy{
+		client.ProviderAge:         {MaxAttempts: cfg.AgeAPIRetryAttempts, BaseDelay: cfg.APIRetryBaseDelay, MaxDelay: cfg.APIRetryMaxDelay},
+		client.ProviderGender:      {MaxAttempts: cfg.GenderAPIRetryAttempts, BaseDelay: cfg.APIRetryBaseDelay, MaxDelay: cfg.APIRetryMaxDelay},
+		client.ProviderNationality: {MaxAttempts: cfg.NationalityAPIRetryAttempts, BaseDelay: cfg.APIRetryBaseDelay, MaxDelay: cfg.APIRetryMaxDelay},
+	}
+	apiClient.Breaker = client.BreakerConfig{
+		ConsecutiveFailures: cfg.BreakerFailureThreshold,
+		ErrorRate:           cfg.BreakerErrorRate,
+		Window:              cfg.BreakerWindow,
+		MinRequests:         cfg.BreakerMinRequests,
+		OpenTimeout:         cfg.BreakerOpenTimeout,
+	}
+	var upstream client.Fetcher = apiClient
+	if cfg.EnrichCoalesce {
+		upstream = client.NewCoalescer(apiClient, cfg.EnrichCoalesceWindow)
+	}
+	var cacheStore *cache.Postgres
+	if cfg.EnrichCachePostgres {
+		cacheStore = cache.NewPostgres(db)
+	}
+	enrichCache := cache.NewFetcher(upstream, cache.NewMemory(cfg.EnrichCacheSize), cacheStore, cache.Config{
+		TTL:         cfg.EnrichCacheTTL,
+		NegativeTTL: cfg.EnrichCacheNegativeTTL,
+	})
+	names := dataset.Sample()
+	if cfg.EnrichDatasetPath != "" {
+		if names, err = dataset.Load(cfg.EnrichDatasetPath); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load enrichment dataset")
+		}
+	}
+	log.Info().Int("names", names.Len()).Msg("Enrichment dataset loaded")
+
+	providers := service.NewRegistry()
+	providers.Register(service.NewFetcherProvider(service.ProviderAPI, enrichCache))
+	providers.Register(service.NewFetcherProvider(service.ProviderDataset, names))
+	providers.Register(service.NewPatronymicProvider())
+	for attr, names := range map[service.Attribute][]string{
+		service.AttributeAge:         cfg.AgeProviders,
+		service.AttributeGender:      cfg.GenderProviders,
+		service.AttributeNationality: cfg.NationalityProviders,
+	} {
+		if err := providers.Use(attr, names...); err != nil {
+			log.Fatal().Err(err).Msg("Invalid enrichment provider configuration")
+		}
+	}
+	personService := service.NewPersonService(db, providers, service.Config{
+		IdempotencyKeyTTL:         cfg.IdempotencyKeyTTL,
+		BatchEnrichConcurrency:    cfg.BatchEnrichConcurrency,
+		BatchMaxSize:              cfg.BatchMaxSize,
+		SearchSimilarityThreshold: cfg.SearchSimilarityThreshold,
+		PurgeRetention:            cfg.PurgeRetention,
+		PurgeEraseHistory:         cfg.PurgeEraseHistory,
+		AsyncEnrichment:           cfg.EnrichAsync,
+		EnrichMaxAttempts:         cfg.EnrichMaxAttempts,
+		EnrichPollInterval:        cfg.EnrichPollInterval,
+		EnrichLockTimeout:         cfg.EnrichLockTimeout,
+		EnrichmentPolicy:          entity.EnrichmentPolicy(cfg.EnrichPolicy),
+		EnrichStaleAfter:          cfg.EnrichStaleAfter,
+		EnrichRetryAfter:          cfg.EnrichRetryAfter,
+		EnrichSweepBatch:          cfg.EnrichSweepBatch,
+		EnrichSweepConcurrency:    cfg.EnrichSweepConcurrency,
+		EnrichSweepRate:           cfg.EnrichSweepRate,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var background sync.WaitGroup
+	background.Add(3)
+	go func() {
+		defer background.Done()
+		personService.RunIdempotencySweeper(ctx, cfg.IdempotencySweepInterval)
+	}()
+	go func() {
+		defer background.Done()
+		personService.RunEnrichmentWorkers(ctx, cfg.EnrichWorkers)
+	}()
+	go func() {
+		defer background.Done()
+		personService.RunEnrichmentSweeper(ctx, cfg.EnrichSweepInterval)
+	}()
+	if cacheStore != nil {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			cacheStore.RunSweeper(ctx, cfg.EnrichCacheSweepInterval)
+		}()
+	}
+	h := handler.NewHandler(personService, enrichCache, apiClient)
 
 	r := chi.NewRouter()
 	r.Post("/api/persons", h.CreatePerson)
+	r.Post("/api/persons/batch", h.CreatePersonsBatch)
+	r.Post("/api/persons/enrich", h.EnrichPersons)
 	r.Get("/api/persons", h.GetPersons)
+	r.Get("/api/persons/{id}", h.GetPerson)
+	r.Get("/api/persons/{id}/history", h.GetPersonHistory)
 	r.Put("/api/persons/{id}", h.UpdatePerson)
+	r.Patch("/api/persons/{id}", h.PatchPerson)
 	r.Delete("/api/persons/{id}", h.DeletePerson)
+	r.Post("/api/persons/{id}/restore", h.RestorePerson)
+	r.Post("/api/persons/{id}/enrich", h.EnrichPerson)
+	// Состояние кэша и внешних API только читается и доступно всегда.
+	r.Get("/api/admin/enrichment/cache", h.GetEnrichmentCacheStats)
+	r.Get("/api/admin/enrichment/providers", h.GetEnrichmentProviderStats)
+	// Окончательное удаление требует ADMIN_TOKEN; без него маршрут не
+	// регистрируется вовсе.
+	if cfg.AdminToken != "" {
+		r.With(handler.AdminAuth(cfg.AdminToken)).Post("/api/admin/persons/purge", h.PurgeDeletedPersons)
+	} else {
+		log.Warn().Msg("ADMIN_TOKEN is not set, purge endpoint is disabled")
+	}
 	r.Get("/swagger/*", httpSwagger.WrapHandler)
 	addr := ":8888"
+	srv := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		<-ctx.Done()
+		log.Info().Msg("Shutting down server ...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("Server shutdown failed")
+		}
+	}()
+
 	log.Info().Msgf("Starting server on %s ...", addr)
-	if err := http.ListenAndServe(addr, r); err != nil {
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal().Err(err).Msg("Server failed")
 	}
+	background.Wait()
 }
@@ -15,6 +15,100 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/admin/enrichment/cache": {
+            "get": {
+                "description": "Попадания по уровням (память, PostgreSQL), попадания в негативные записи и промахи с момента запуска.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Счётчики кэша обогащения",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/cache.Stats"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/enrichment/providers": {
+            "get": {
+                "description": "Для каждого API: состояние circuit breaker (closed, open, half-open) и счётчики с момента запуска — запросы, повторные попытки, запросы, не удавшиеся после всех попыток, и запросы, отклонённые открытым breaker'ом.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Состояние внешних API",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "$ref": "#/definitions/client.ProviderStats"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/persons/purge": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Физически удаляет записи, мягко удалённые раньше срока хранения (PURGE_RETENTION или olderThan). История изменений удалённых записей сохраняется; при PURGE_ERASE_HISTORY=true она стирается и остаётся только отметка purge без данных.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Окончательно удалить давно удалённых людей",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Срок хранения, например 720h",
+                        "name": "olderThan",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entity.PurgeResult"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/api/persons": {
             "get": {
                 "consumes": [
@@ -28,6 +122,12 @@ const docTemplate = `{
                 ],
                 "summary": "Получить список людей с фильтрами и пагинацией",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Нечёткий поиск по ФИО с учётом опечаток и транслитерации",
+                        "name": "q",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Имя",
@@ -40,6 +140,12 @@ const docTemplate = `{
                         "name": "surname",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "Отчество",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Пол",
@@ -64,6 +170,36 @@ const docTemplate = `{
                         "name": "maxAge",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "Статус обогащения: pending, done, partial или failed",
+                        "name": "enrichmentStatus",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только записи с незаполненным возрастом, полом или национальностью",
+                        "name": "missing",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Мин. вероятность пола (0..1)",
+                        "name": "minGenderProbability",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Мин. вероятность основной национальности (0..1)",
+                        "name": "minNationalityProbability",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Мин. число наблюдений, на которых основан возраст",
+                        "name": "minAgeSampleCount",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
                         "description": "Страница",
@@ -75,22 +211,49 @@ const docTemplate = `{
                         "description": "Размер страницы",
                         "name": "pageSize",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор keyset-пагинации (пустое значение — первая страница)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подсчёт total: exact (по умолчанию), estimated или none",
+                        "name": "count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Включить мягко удалённые записи",
+                        "name": "includeDeleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Сортировка через запятую, минус — по убыванию: surname,-age,created_at",
+                        "name": "sort",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/entity.Person"
-                            }
+                            "$ref": "#/definitions/entity.PersonList"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Добавляет человека с обогащением через внешние API",
+                "description": "Переданные age, gender и nationality сохраняются как ручные (attribute_sources) и не перезаписываются обогащением.\nЗаголовок Idempotency-Key делает запрос безопасным для повтора: повтор с тем же ключом и телом возвращает исходный ответ.\nВ асинхронном режиме (async=true или Prefer: respond-async) запись сохраняется сразу со статусом обогащения pending и возвращается 202.",
                 "consumes": [
                     "application/json"
                 ],
@@ -108,8 +271,26 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/entity.Person"
+                            "$ref": "#/definitions/entity.CreatePersonInput"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Обогатить запись в фоне",
+                        "name": "async",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "respond-async — обогатить запись в фоне",
+                        "name": "Prefer",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -119,23 +300,48 @@ const docTemplate = `{
                             "$ref": "#/definitions/entity.Person"
                         }
                     },
+                    "202": {
+                        "description": "обогащение поставлено в очередь",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Person"
+                        }
+                    },
                     "400": {
                         "description": "bad request",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "422": {
+                        "description": "idempotency key reused with different body or enrichment mode",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "server error",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "502": {
+                        "description": "внешний API обогащения вернул ошибку",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "лимит внешнего API исчерпан (см. Retry-After) или открыт circuit breaker",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/api/persons/{id}": {
-            "put": {
+        "/api/persons/batch": {
+            "post": {
+                "description": "ФИО обогащаются параллельно, одинаковые ФИО обогащаются один раз, а каждое имя запрашивается у провайдера, зависящего только от имени, один раз на пакет. В режиме atomic пакет сохраняется в одной транзакции, в режиме per_item — поэлементно.",
                 "consumes": [
                     "application/json"
                 ],
@@ -145,30 +351,44 @@ const docTemplate = `{
                 "tags": [
                     "persons"
                 ],
-                "summary": "Обновить данные человека по id",
+                "summary": "Создать пакет людей",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Новые данные",
-                        "name": "person",
+                        "description": "Персоны",
+                        "name": "persons",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/entity.Person"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.CreatePersonInput"
+                            }
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Режим сохранения: atomic (по умолчанию) или per_item",
+                        "name": "mode",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/entity.Person"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.BatchItemResult"
+                            }
+                        }
+                    },
+                    "207": {
+                        "description": "часть элементов не сохранена",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.BatchItemResult"
+                            }
                         }
                     },
                     "400": {
@@ -177,10 +397,13 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "not found",
+                    "422": {
+                        "description": "атомарный пакет не сохранён",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.BatchItemResult"
+                            }
                         }
                     },
                     "500": {
@@ -190,93 +413,1068 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/api/persons/enrich": {
+            "post": {
+                "description": "Ставит подходящие записи в очередь фонового обогащения. Нужен хотя бы один фильтр; записи, уже стоящие в очереди, пропускаются.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "persons"
                 ],
-                "summary": "Удалить человека по id",
+                "summary": "Заново обогатить людей по фильтру",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "204": {
-                        "description": "No Content",
-                        "schema": {
-                            "type": "string"
-                        }
+                        "type": "string",
+                        "description": "Нечёткий поиск по ФИО с учётом опечаток и транслитерации",
+                        "name": "q",
+                        "in": "query"
                     },
-                    "400": {
-                        "description": "bad request",
-                        "schema": {
-                            "type": "string"
-                        }
+                    {
+                        "type": "string",
+                        "description": "Имя",
+                        "name": "name",
+                        "in": "query"
                     },
-                    "404": {
-                        "description": "not found",
-                        "schema": {
-                            "type": "string"
-                        }
+                    {
+                        "type": "string",
+                        "description": "Фамилия",
+                        "name": "surname",
+                        "in": "query"
                     },
-                    "500": {
-                        "description": "server error",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            }
-        }
-    },
-    "definitions": {
-        "entity.Person": {
-            "type": "object",
-            "required": [
-                "name",
-                "surname"
-            ],
-            "properties": {
-                "age": {
-                    "type": "integer"
-                },
-                "created_at": {
-                    "type": "string"
-                },
-                "gender": {
+                    {
+                        "type": "string",
+                        "description": "Отчество",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Мин. возраст",
+                        "name": "minAge",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Макс. возраст",
+                        "name": "maxAge",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Статус обогащения: pending, done, partial или failed",
+                        "name": "enrichmentStatus",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только записи с незаполненным возрастом, полом или национальностью",
+                        "name": "missing",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Мин. вероятность пола (0..1)",
+                        "name": "minGenderProbability",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Мин. вероятность основной национальности (0..1)",
+                        "name": "minNationalityProbability",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Мин. число наблюдений, на которых основан возраст",
+                        "name": "minAgeSampleCount",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/entity.EnrichQueued"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/persons/{id}": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Получить человека по id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Восстановить состояние на момент времени (RFC 3339)",
+                        "name": "asOf",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag закэшированной версии",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Person"
+                        }
+                    },
+                    "304": {
+                        "description": "not modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Запись заменяется целиком. Переданные age, gender и nationality сохраняются как ручные (attribute_sources) и не перезаписываются обогащением; непереданные заполняются обогащением заново.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Обновить данные человека по id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новые данные",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.UpdatePersonInput"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag, полученный при чтении записи",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "precondition failed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "внешний API обогащения вернул ошибку",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "лимит внешнего API исчерпан (см. Retry-After) или открыт circuit breaker",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаление мягкое: запись скрывается из выдачи и может быть восстановлена до очистки.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Удалить человека по id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag, полученный при чтении записи",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "precondition failed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Изменяются только переданные поля, явный null очищает значение. Переданные age, gender и nationality становятся ручными и не перезаписываются обогащением; null снимает ручное значение.\nОбогащение перезапускается при смене имени или снятии ручного значения.",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Частично обновить данные человека (JSON Merge Patch, RFC 7396)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Изменяемые поля",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.PatchPersonInput"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag, полученный при чтении записи",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "precondition failed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "unsupported media type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "внешний API обогащения вернул ошибку",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "лимит внешнего API исчерпан (см. Retry-After) или открыт circuit breaker",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/persons/{id}/enrich": {
+            "post": {
+                "description": "Повторно запрашивает возраст, пол и национальность. Атрибуты, которые не удалось получить из-за ошибки API, сохраняют прежние значения.\nВ асинхронном режиме (async=true или Prefer: respond-async) запись ставится в очередь фонового обогащения и возвращается 202.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Заново обогатить человека",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Обогатить запись в фоне",
+                        "name": "async",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "respond-async — обогатить запись в фоне",
+                        "name": "Prefer",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Person"
+                        }
+                    },
+                    "202": {
+                        "description": "обогащение поставлено в очередь",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "внешний API отклонил имя",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "внешний API обогащения вернул ошибку",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "лимит внешнего API исчерпан (см. Retry-After) или открыт circuit breaker",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "504": {
+                        "description": "внешний API не ответил вовремя",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/persons/{id}/history": {
+            "get": {
+                "description": "Каждая запись содержит операцию, источник изменения, время и значения до и после. Окончательное удаление (purge) добавляет отметку purge с последними данными записи; при PURGE_ERASE_HISTORY=true история стирается и остаётся только отметка purge без данных.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Получить историю изменений человека",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.PersonHistoryEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/persons/{id}/restore": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Восстановить мягко удалённого человека",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "person is not deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "cache.Stats": {
+            "type": "object",
+            "properties": {
+                "memory_entries": {
+                    "type": "integer"
+                },
+                "memory_hits": {
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                },
+                "negative_hits": {
+                    "description": "из них попаданий в негативные записи",
+                    "type": "integer"
+                },
+                "postgres_hits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "client.CircuitState": {
+            "type": "string",
+            "enum": [
+                "closed",
+                "open",
+                "half-open"
+            ],
+            "x-enum-comments": {
+                "CircuitClosed": "requests pass through",
+                "CircuitHalfOpen": "a single probe request is allowed",
+                "CircuitOpen": "requests fail fast"
+            },
+            "x-enum-varnames": [
+                "CircuitClosed",
+                "CircuitOpen",
+                "CircuitHalfOpen"
+            ]
+        },
+        "client.ProviderStats": {
+            "type": "object",
+            "properties": {
+                "circuit": {
+                    "$ref": "#/definitions/client.CircuitState"
+                },
+                "failures": {
+                    "description": "Failures counts requests that still failed after all attempts.",
+                    "type": "integer"
+                },
+                "rejected": {
+                    "description": "Rejected counts requests failed fast by the open circuit breaker.",
+                    "type": "integer"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "retries": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entity.AttributeSource": {
+            "type": "string",
+            "enum": [
+                "enriched",
+                "manual"
+            ],
+            "x-enum-comments": {
+                "SourceEnriched": "получено обогащением",
+                "SourceManual": "задано оператором, обогащение его не перезаписывает"
+            },
+            "x-enum-varnames": [
+                "SourceEnriched",
+                "SourceManual"
+            ]
+        },
+        "entity.AttributeSources": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "$ref": "#/definitions/entity.AttributeSource"
+                },
+                "gender": {
+                    "$ref": "#/definitions/entity.AttributeSource"
+                },
+                "nationality": {
+                    "$ref": "#/definitions/entity.AttributeSource"
+                }
+            }
+        },
+        "entity.AttributeState": {
+            "type": "string",
+            "enum": [
+                "ok",
+                "missing",
+                "error"
+            ],
+            "x-enum-comments": {
+                "AttributeError": "API недоступен или вернул ошибку",
+                "AttributeMissing": "API ответил, но значения нет",
+                "AttributeOK": "значение получено"
+            },
+            "x-enum-varnames": [
+                "AttributeOK",
+                "AttributeMissing",
+                "AttributeError"
+            ]
+        },
+        "entity.BatchItemResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "person": {
+                    "$ref": "#/definitions/entity.Person"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entity.ChangeSource": {
+            "type": "string",
+            "enum": [
+                "api",
+                "enrichment",
+                "import",
+                "admin"
+            ],
+            "x-enum-varnames": [
+                "ChangeSourceAPI",
+                "ChangeSourceEnrichment",
+                "ChangeSourceImport",
+                "ChangeSourceAdmin"
+            ]
+        },
+        "entity.CountMode": {
+            "type": "string",
+            "enum": [
+                "exact",
+                "estimated",
+                "none"
+            ],
+            "x-enum-comments": {
+                "CountEstimated": "оценка планировщика PostgreSQL",
+                "CountExact": "COUNT(*) по фильтру",
+                "CountNone": "без подсчёта"
+            },
+            "x-enum-varnames": [
+                "CountExact",
+                "CountEstimated",
+                "CountNone"
+            ]
+        },
+        "entity.CreatePersonInput": {
+            "type": "object",
+            "required": [
+                "name",
+                "surname"
+            ],
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nationality": {
+                    "type": "string"
+                },
+                "patronymic": {
+                    "type": "string"
+                },
+                "surname": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.EnrichQueued": {
+            "type": "object",
+            "properties": {
+                "queued": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entity.EnrichmentState": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "$ref": "#/definitions/entity.AttributeState"
+                },
+                "gender": {
+                    "$ref": "#/definitions/entity.AttributeState"
+                },
+                "nationality": {
+                    "$ref": "#/definitions/entity.AttributeState"
+                }
+            }
+        },
+        "entity.EnrichmentStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "done",
+                "partial",
+                "failed"
+            ],
+            "x-enum-comments": {
+                "EnrichmentFailed": "не получен ни один атрибут, см. enrichment_error",
+                "EnrichmentPartial": "часть атрибутов не получена, см. enrichment_state",
+                "EnrichmentPending": "ожидает фонового обогащения"
+            },
+            "x-enum-varnames": [
+                "EnrichmentPending",
+                "EnrichmentDone",
+                "EnrichmentPartial",
+                "EnrichmentFailed"
+            ]
+        },
+        "entity.NationalityProbability": {
+            "type": "object",
+            "properties": {
+                "country_id": {
+                    "type": "string"
+                },
+                "probability": {
+                    "type": "number"
+                }
+            }
+        },
+        "entity.PageLinks": {
+            "type": "object",
+            "properties": {
+                "next": {
+                    "type": "string"
+                },
+                "prev": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.PatchPersonInput": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nationality": {
+                    "type": "string"
+                },
+                "patronymic": {
+                    "type": "string"
+                },
+                "surname": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.Person": {
+            "type": "object",
+            "required": [
+                "name",
+                "surname"
+            ],
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "age_sample_count": {
+                    "type": "integer"
+                },
+                "attribute_sources": {
+                    "description": "AttributeSources показывает, какие атрибуты заданы вручную.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.AttributeSources"
+                        }
+                    ]
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "enriched_at": {
+                    "description": "EnrichedAt — время последнего обогащения, в котором получен хотя бы\nодин атрибут.",
+                    "type": "string"
+                },
+                "enrichment_error": {
+                    "type": "string"
+                },
+                "enrichment_state": {
+                    "description": "EnrichmentState показывает, какие атрибуты не удалось обогатить и их\nстоит запросить повторно.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entity.EnrichmentState"
+                        }
+                    ]
+                },
+                "enrichment_status": {
+                    "$ref": "#/definitions/entity.EnrichmentStatus"
+                },
+                "gender": {
                     "type": "string"
                 },
+                "gender_probability": {
+                    "description": "Достоверность обогащённых значений. Для значений, заданных вручную,\nне заполняется.",
+                    "type": "number"
+                },
                 "id": {
                     "type": "integer"
                 },
                 "name": {
                     "type": "string"
                 },
+                "nationalities": {
+                    "description": "по убыванию вероятности",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.NationalityProbability"
+                    }
+                },
                 "nationality": {
                     "type": "string"
                 },
                 "patronymic": {
                     "type": "string"
                 },
+                "score": {
+                    "description": "релевантность при поиске по q",
+                    "type": "number"
+                },
                 "surname": {
                     "type": "string"
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "version": {
+                    "description": "используется как ETag для optimistic concurrency",
+                    "type": "integer"
+                }
+            }
+        },
+        "entity.PersonHistoryEntry": {
+            "type": "object",
+            "properties": {
+                "changed_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "new_data": {
+                    "type": "object"
+                },
+                "old_data": {
+                    "type": "object"
+                },
+                "operation": {
+                    "description": "insert, update, delete, restore, purge",
+                    "type": "string"
+                },
+                "person_id": {
+                    "type": "integer"
+                },
+                "source": {
+                    "$ref": "#/definitions/entity.ChangeSource"
+                }
+            }
+        },
+        "entity.PersonList": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "$ref": "#/definitions/entity.CountMode"
+                },
+                "has_more": {
+                    "type": "boolean"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.Person"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/entity.PageLinks"
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "page": {
+                    "description": "не заполняется при пагинации курсором",
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "null при count=none",
+                    "type": "integer"
+                }
+            }
+        },
+        "entity.PurgeResult": {
+            "type": "object",
+            "properties": {
+                "purged": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entity.UpdatePersonInput": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nationality": {
+                    "type": "string"
+                },
+                "patronymic": {
+                    "type": "string"
+                },
+                "surname": {
+                    "type": "string"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "Bearer-токен из ADMIN_TOKEN: \"Bearer \u003ctoken\u003e\".",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -0,0 +1,187 @@
+// Package cache кэширует ответы внешних API обогащения по нормализованному
+// имени: первый уровень — LRU в памяти процесса, второй — таблица
+// enrichment_cache в PostgreSQL.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/k1lls3x/person-service/internal/client"
+)
+
+const (
+	AttributeAge         = "age"
+	AttributeGender      = "gender"
+	AttributeNationality = "nationality"
+)
+
+// Key — ключ кэша: атрибут и нормализованное имя.
+type Key struct {
+	Attribute string
+	Name      string
+}
+
+// Entry — закэшированный ответ API в JSON. Value == nil — API ответил, что
+// данных нет (негативное кэширование).
+type Entry struct {
+	Value     *string
+	ExpiresAt time.Time
+}
+
+func (e Entry) expired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
+
+// Stats — счётчики обращений к кэшу с момента запуска.
+type Stats struct {
+	MemoryHits    int64 `json:"memory_hits"`
+	PostgresHits  int64 `json:"postgres_hits"`
+	NegativeHits  int64 `json:"negative_hits"` // из них попаданий в негативные записи
+	Misses        int64 `json:"misses"`
+	MemoryEntries int   `json:"memory_entries"`
+}
+
+// Config задаёт время жизни записей.
+type Config struct {
+	// TTL — время жизни найденного значения.
+	TTL time.Duration
+	// NegativeTTL — время жизни записи «данных нет».
+	NegativeTTL time.Duration
+}
+
+// Fetcher реализует client.Fetcher поверх другого Fetcher, кэшируя
+// успешные ответы. Ошибки не кэшируются.
+type Fetcher struct {
+	next     client.Fetcher
+	memory   *Memory
+	postgres *Postgres
+	cfg      Config
+
+	memoryHits   atomic.Int64
+	postgresHits atomic.Int64
+	negativeHits atomic.Int64
+	misses       atomic.Int64
+}
+
+// NewFetcher создаёт кэширующий Fetcher. postgres может быть nil — тогда
+// используется только кэш в памяти.
+func NewFetcher(next client.Fetcher, memory *Memory, postgres *Postgres, cfg Config) *Fetcher {
+	return &Fetcher{next: next, memory: memory, postgres: postgres, cfg: cfg}
+}
+
+// NormalizeName приводит имя к виду, используемому в ключе кэша.
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (f *Fetcher) FetchAge(ctx context.Context, name string) (*client.AgeResult, error) {
+	return lookup(ctx, f, Key{AttributeAge, NormalizeName(name)}, func(ctx context.Context) (*client.AgeResult, error) {
+		return f.next.FetchAge(ctx, name)
+	})
+}
+
+// FetchGender кэширует ответ без пола (client.GenderUnknown) как негативный:
+// с NegativeTTL и возвратом nil, как и любое «данных нет».
+func (f *Fetcher) FetchGender(ctx context.Context, name string) (*client.GenderResult, error) {
+	return lookup(ctx, f, Key{AttributeGender, NormalizeName(name)}, func(ctx context.Context) (*client.GenderResult, error) {
+		res, err := f.next.FetchGender(ctx, name)
+		if res != nil && res.Gender == client.GenderUnknown {
+			return nil, err
+		}
+		return res, err
+	})
+}
+
+func (f *Fetcher) FetchNationality(ctx context.Context, name string) (*client.NationalityResult, error) {
+	return lookup(ctx, f, Key{AttributeNationality, NormalizeName(name)}, func(ctx context.Context) (*client.NationalityResult, error) {
+		return f.next.FetchNationality(ctx, name)
+	})
+}
+
+// lookup ищет ключ сначала в памяти, затем в PostgreSQL, и только при
+// промахе обращается к fetch. Значения хранятся в JSON. Ошибка PostgreSQL
+// не прерывает обогащение: запрос уходит во внешний API.
+func lookup[T any](ctx context.Context, f *Fetcher, key Key, fetch func(ctx context.Context) (*T, error)) (*T, error) {
+	now := time.Now()
+
+	if entry, ok := f.memory.Get(key, now); ok {
+		if value, err := decodeEntry[T](entry); err == nil {
+			f.memoryHits.Add(1)
+			f.countNegative(entry)
+			return value, nil
+		}
+	}
+
+	if f.postgres != nil {
+		entry, ok, err := f.postgres.Get(ctx, key)
+		if err != nil {
+			log.Warn().Err(err).Str("attribute", key.Attribute).Str("name", key.Name).Msg("Enrichment cache lookup failed")
+		} else if ok {
+			if value, err := decodeEntry[T](entry); err == nil {
+				f.postgresHits.Add(1)
+				f.countNegative(entry)
+				f.memory.Set(key, entry)
+				return value, nil
+			}
+		}
+	}
+
+	f.misses.Add(1)
+	value, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := Entry{ExpiresAt: now.Add(f.cfg.NegativeTTL)}
+	if value != nil {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		s := string(raw)
+		entry = Entry{Value: &s, ExpiresAt: now.Add(f.cfg.TTL)}
+	}
+	f.memory.Set(key, entry)
+	if f.postgres != nil {
+		if err := f.postgres.Set(ctx, key, entry); err != nil {
+			log.Warn().Err(err).Str("attribute", key.Attribute).Str("name", key.Name).Msg("Failed to store enrichment cache entry")
+		}
+	}
+	return value, nil
+}
+
+// decodeEntry разбирает закэшированное значение. Запись, которую не удалось
+// разобрать (например, сохранённую в старом формате), считается промахом.
+func decodeEntry[T any](entry Entry) (*T, error) {
+	if entry.Value == nil {
+		return nil, nil
+	}
+	var value T
+	if err := json.Unmarshal([]byte(*entry.Value), &value); err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+func (f *Fetcher) countNegative(entry Entry) {
+	if entry.Value == nil {
+		f.negativeHits.Add(1)
+	}
+}
+
+// Stats возвращает текущие значения счётчиков.
+func (f *Fetcher) Stats() Stats {
+	return Stats{
+		MemoryHits:    f.memoryHits.Load(),
+		PostgresHits:  f.postgresHits.Load(),
+		NegativeHits:  f.negativeHits.Load(),
+		Misses:        f.misses.Load(),
+		MemoryEntries: f.memory.Len(),
+	}
+}
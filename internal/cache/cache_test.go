@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/k1lls3x/person-service/internal/client"
+)
+
+// stubFetcher отвечает заданными значениями и считает обращения.
+type stubFetcher struct {
+	age    *client.AgeResult
+	gender *client.GenderResult
+	calls  int
+}
+
+func (s *stubFetcher) FetchAge(context.Context, string) (*client.AgeResult, error) {
+	s.calls++
+	return s.age, nil
+}
+
+func (s *stubFetcher) FetchGender(context.Context, string) (*client.GenderResult, error) {
+	s.calls++
+	return s.gender, nil
+}
+
+func (s *stubFetcher) FetchNationality(context.Context, string) (*client.NationalityResult, error) {
+	s.calls++
+	return nil, nil
+}
+
+var testConfig = Config{TTL: 30 * 24 * time.Hour, NegativeTTL: 24 * time.Hour}
+
+func TestFetcherCachesValues(t *testing.T) {
+	next := &stubFetcher{age: &client.AgeResult{Age: 36, Count: 10}}
+	f := NewFetcher(next, NewMemory(10), nil, testConfig)
+
+	for range 2 {
+		res, err := f.FetchAge(context.Background(), " Ivan ")
+		if err != nil || res == nil || res.Age != 36 {
+			t.Fatalf("FetchAge = %+v, %v; want age 36", res, err)
+		}
+	}
+	if next.calls != 1 {
+		t.Errorf("upstream called %d times, want 1", next.calls)
+	}
+	if stats := f.Stats(); stats.MemoryHits != 1 || stats.Misses != 1 || stats.NegativeHits != 0 {
+		t.Errorf("stats = %+v, want 1 hit and 1 miss", stats)
+	}
+
+	entry, ok := f.memory.Get(Key{AttributeAge, "ivan"}, time.Now())
+	if !ok || entry.Value == nil || time.Until(entry.ExpiresAt) < testConfig.TTL-time.Minute {
+		t.Errorf("entry = %+v, want a positive entry with TTL", entry)
+	}
+}
+
+func TestFetcherCachesUnknownGenderAsNegative(t *testing.T) {
+	next := &stubFetcher{gender: &client.GenderResult{Gender: client.GenderUnknown}}
+	f := NewFetcher(next, NewMemory(10), nil, testConfig)
+
+	for range 2 {
+		res, err := f.FetchGender(context.Background(), "Zebulon")
+		if err != nil || res != nil {
+			t.Fatalf("FetchGender = %+v, %v; want no data", res, err)
+		}
+	}
+	if next.calls != 1 {
+		t.Errorf("upstream called %d times, want 1", next.calls)
+	}
+	if stats := f.Stats(); stats.NegativeHits != 1 {
+		t.Errorf("stats = %+v, want 1 negative hit", stats)
+	}
+
+	entry, ok := f.memory.Get(Key{AttributeGender, "zebulon"}, time.Now())
+	if !ok || entry.Value != nil {
+		t.Fatalf("entry = %+v, want a negative entry", entry)
+	}
+	if ttl := time.Until(entry.ExpiresAt); ttl > testConfig.NegativeTTL {
+		t.Errorf("negative entry lives %v, want at most %v", ttl, testConfig.NegativeTTL)
+	}
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Memory — LRU-кэш в памяти процесса с ограничением по числу записей.
+// Просроченные записи удаляются при обращении к ним.
+type Memory struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[Key]*list.Element
+}
+
+type memoryItem struct {
+	key   Key
+	entry Entry
+}
+
+func NewMemory(capacity int) *Memory {
+	return &Memory{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[Key]*list.Element, capacity),
+	}
+}
+
+func (m *Memory) Get(key Key, now time.Time) (Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	item := el.Value.(*memoryItem)
+	if item.entry.expired(now) {
+		m.order.Remove(el)
+		delete(m.items, key)
+		return Entry{}, false
+	}
+	m.order.MoveToFront(el)
+	return item.entry, true
+}
+
+func (m *Memory) Set(key Key, entry Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.items[key]; ok {
+		el.Value.(*memoryItem).entry = entry
+		m.order.MoveToFront(el)
+		return
+	}
+	m.items[key] = m.order.PushFront(&memoryItem{key: key, entry: entry})
+	for m.order.Len() > m.capacity {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.items, oldest.Value.(*memoryItem).key)
+	}
+}
+
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Now()
+	entry := Entry{ExpiresAt: now.Add(time.Hour)}
+	m := NewMemory(2)
+
+	ivan, olga, petr := Key{AttributeAge, "ivan"}, Key{AttributeAge, "olga"}, Key{AttributeAge, "petr"}
+	m.Set(ivan, entry)
+	m.Set(olga, entry)
+	// Обращение к ivan делает самой старой записью olga.
+	if _, ok := m.Get(ivan, now); !ok {
+		t.Fatal("ivan is missing before eviction")
+	}
+	m.Set(petr, entry)
+
+	if m.Len() != 2 {
+		t.Errorf("Len = %d, want 2", m.Len())
+	}
+	if _, ok := m.Get(olga, now); ok {
+		t.Error("olga was not evicted")
+	}
+	for _, key := range []Key{ivan, petr} {
+		if _, ok := m.Get(key, now); !ok {
+			t.Errorf("%s was evicted", key.Name)
+		}
+	}
+}
+
+func TestMemorySetReplacesEntry(t *testing.T) {
+	now := time.Now()
+	key := Key{AttributeGender, "ivan"}
+	value := "male"
+	m := NewMemory(2)
+
+	m.Set(key, Entry{ExpiresAt: now.Add(time.Hour)})
+	m.Set(key, Entry{Value: &value, ExpiresAt: now.Add(time.Hour)})
+
+	entry, ok := m.Get(key, now)
+	if !ok || entry.Value == nil || *entry.Value != "male" {
+		t.Errorf("entry = %+v, want the replaced value", entry)
+	}
+	if m.Len() != 1 {
+		t.Errorf("Len = %d, want 1", m.Len())
+	}
+}
+
+func TestMemoryDropsExpiredEntries(t *testing.T) {
+	now := time.Now()
+	key := Key{AttributeAge, "ivan"}
+	m := NewMemory(2)
+	m.Set(key, Entry{ExpiresAt: now.Add(time.Minute)})
+
+	if _, ok := m.Get(key, now.Add(time.Second)); !ok {
+		t.Fatal("entry expired before its TTL")
+	}
+	if _, ok := m.Get(key, now.Add(time.Minute)); ok {
+		t.Error("entry outlived its TTL")
+	}
+	if m.Len() != 0 {
+		t.Errorf("Len = %d, want the expired entry removed", m.Len())
+	}
+}
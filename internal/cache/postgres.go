@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+// Postgres — второй уровень кэша в таблице enrichment_cache. Переживает
+// перезапуск и общий для всех экземпляров сервиса.
+type Postgres struct {
+	db *sqlx.DB
+}
+
+func NewPostgres(db *sqlx.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (p *Postgres) Get(ctx context.Context, key Key) (Entry, bool, error) {
+	var entry Entry
+	err := p.db.QueryRowxContext(ctx, `
+		SELECT value, expires_at FROM enrichment_cache
+		WHERE attribute = $1 AND name = $2 AND expires_at > NOW()
+	`, key.Attribute, key.Name).Scan(&entry.Value, &entry.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+	return entry, true, nil
+}
+
+func (p *Postgres) Set(ctx context.Context, key Key, entry Entry) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO enrichment_cache (attribute, name, value, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (attribute, name) DO UPDATE
+		SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at, created_at = NOW()
+	`, key.Attribute, key.Name, entry.Value, entry.ExpiresAt)
+	return err
+}
+
+// PurgeExpired удаляет просроченные записи.
+func (p *Postgres) PurgeExpired(ctx context.Context) (int64, error) {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM enrichment_cache WHERE expires_at <= NOW()`)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to purge expired enrichment cache")
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RunSweeper периодически удаляет просроченные записи, пока ctx не отменён.
+func (p *Postgres) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := p.PurgeExpired(ctx)
+			if err != nil {
+				continue
+			}
+			if n > 0 {
+				log.Info().Int64("count", n).Msg("Purged expired enrichment cache entries")
+			}
+		}
+	}
+}
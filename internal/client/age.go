@@ -9,7 +9,26 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-func (c *APIClient) FetchAge(ctx context.Context, name string) (*int, error) {
+// AgeResult is the agify answer for a name.
+type AgeResult struct {
+	Age int `json:"age"`
+	// Count is the number of samples the estimate is based on.
+	Count int `json:"count"`
+}
+
+type ageResponse struct {
+	Age   *int `json:"age"`
+	Count int  `json:"count"`
+}
+
+func (r ageResponse) result() *AgeResult {
+	if r.Age == nil {
+		return nil
+	}
+	return &AgeResult{Age: *r.Age, Count: r.Count}
+}
+
+func (c *APIClient) FetchAge(ctx context.Context, name string) (*AgeResult, error) {
 	apiURL := c.AgeURL + "?name=" + url.PathEscape(name)
 
 	log.Info().Str("name", name).Msg("Fetching age from API")
@@ -21,26 +40,29 @@ func (c *APIClient) FetchAge(ctx context.Context, name string) (*int, error) {
 		return nil, err
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req, ProviderAge)
 	if err != nil {
 		log.Error().Err(err).Str("url", apiURL).Msg("Failed to send request to external API")
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var result struct {
-		Age *int `json:"age"`
+	if err := checkResponse(resp, ProviderAge); err != nil {
+		log.Error().Err(err).Str("url", apiURL).Msg("External API returned an error")
+		return nil, err
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	var response ageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		log.Error().Err(err).Str("url", apiURL).Msg("Failed to decode response from API")
 		return nil, err
 	}
 
-	if result.Age != nil {
-		log.Info().Str("name", name).Int("age", *result.Age).Msg("Successfully fetched age from API")
+	result := response.result()
+	if result != nil {
+		log.Info().Str("name", name).Int("age", result.Age).Int("count", result.Count).Msg("Successfully fetched age from API")
 	} else {
 		log.Info().Str("name", name).Msg("No age returned from API")
 	}
-	return result.Age, nil
+	return result, nil
 }
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MaxBatchSize is the maximum number of names the upstream APIs accept in a
+// single request.
+const MaxBatchSize = 10
+
+// BatchFetcher resolves attributes for several names at once. The result maps
+// every requested name to its value; a nil value means the upstream has no
+// data for the name.
+type BatchFetcher interface {
+	FetchAges(ctx context.Context, names []string) (map[string]*AgeResult, error)
+	FetchGenders(ctx context.Context, names []string) (map[string]*GenderResult, error)
+	FetchNationalities(ctx context.Context, names []string) (map[string]*NationalityResult, error)
+}
+
+func (c *APIClient) FetchAges(ctx context.Context, names []string) (map[string]*AgeResult, error) {
+	return fetchBatch(ctx, c, ProviderAge, c.AgeURL, names, ageResponse.result)
+}
+
+func (c *APIClient) FetchGenders(ctx context.Context, names []string) (map[string]*GenderResult, error) {
+	return fetchBatch(ctx, c, ProviderGender, c.GenderURL, names, genderResponse.result)
+}
+
+func (c *APIClient) FetchNationalities(ctx context.Context, names []string) (map[string]*NationalityResult, error) {
+	return fetchBatch(ctx, c, ProviderNationality, c.NationalityURL, names, nationalityResponse.result)
+}
+
+// fetchBatch requests names in chunks of MaxBatchSize using repeated name[]
+// parameters. The upstream answers with an array in request order, so items
+// are mapped back to names by position.
+func fetchBatch[I any, V any](ctx context.Context, c *APIClient, provider, baseURL string, names []string, value func(I) *V) (map[string]*V, error) {
+	result := make(map[string]*V, len(names))
+	for start := 0; start < len(names); start += MaxBatchSize {
+		chunk := names[start:min(start+MaxBatchSize, len(names))]
+
+		query := url.Values{"name[]": chunk}
+		apiURL := baseURL + "?" + query.Encode()
+
+		log.Info().Strs("names", chunk).Msg("Fetching batch from API")
+		log.Debug().Str("url", apiURL).Msg("Sending batch request to external API")
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+		if err != nil {
+			log.Error().Err(err).Str("url", apiURL).Msg("Failed to create HTTP request")
+			return nil, err
+		}
+
+		resp, err := c.do(req, provider)
+		if err != nil {
+			log.Error().Err(err).Str("url", apiURL).Msg("Failed to send request to external API")
+			return nil, err
+		}
+
+		if err := checkResponse(resp, provider); err != nil {
+			resp.Body.Close()
+			log.Error().Err(err).Str("url", apiURL).Msg("External API returned an error")
+			return nil, err
+		}
+
+		var items []I
+		err = json.NewDecoder(resp.Body).Decode(&items)
+		resp.Body.Close()
+		if err != nil {
+			log.Error().Err(err).Str("url", apiURL).Msg("Failed to decode response from API")
+			return nil, err
+		}
+		if len(items) != len(chunk) {
+			return nil, fmt.Errorf("batch response has %d items for %d names", len(items), len(chunk))
+		}
+
+		for i, item := range items {
+			result[chunk[i]] = value(item)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrCircuitOpen is returned without calling the upstream while the
+// provider's circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a provider's circuit breaker.
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // requests pass through
+	CircuitOpen     CircuitState = "open"      // requests fail fast
+	CircuitHalfOpen CircuitState = "half-open" // a single probe request is allowed
+)
+
+// BreakerConfig controls when a provider's circuit breaker trips. Transport
+// errors, 429 and 5xx responses count as failures.
+type BreakerConfig struct {
+	// ConsecutiveFailures trips the breaker after that many failures in a row.
+	ConsecutiveFailures int
+	// ErrorRate trips the breaker when the share of failures among the last
+	// Window requests reaches it, provided at least MinRequests were made.
+	ErrorRate   float64
+	Window      int
+	MinRequests int
+	// OpenTimeout is how long the breaker stays open before a probe request
+	// is let through.
+	OpenTimeout time.Duration
+}
+
+// DefaultBreakerConfig is used by NewAPIClient.
+var DefaultBreakerConfig = BreakerConfig{
+	ConsecutiveFailures: 5,
+	ErrorRate:           0.5,
+	Window:              20,
+	MinRequests:         10,
+	OpenTimeout:         30 * time.Second,
+}
+
+type breaker struct {
+	provider string
+
+	mu          sync.Mutex
+	state       CircuitState
+	openedAt    time.Time
+	probing     bool
+	consecutive int
+	outcomes    []bool // ring buffer of recent results, true means failure
+	next        int
+	filled      int
+}
+
+func newBreaker(provider string) *breaker {
+	return &breaker{provider: provider, state: CircuitClosed}
+}
+
+// allow reports whether a request may be sent now. In the half-open state only
+// one probe request is in flight at a time.
+func (b *breaker) allow(cfg BreakerConfig, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen {
+		if now.Sub(b.openedAt) < cfg.OpenTimeout {
+			return false
+		}
+		b.setState(CircuitHalfOpen)
+	}
+	if b.state == CircuitHalfOpen {
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// record registers the outcome of an allowed request.
+func (b *breaker) record(cfg BreakerConfig, failed bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitHalfOpen:
+		b.probing = false
+		if failed {
+			b.trip(now)
+		} else {
+			b.reset()
+			b.setState(CircuitClosed)
+		}
+		return
+	case CircuitOpen:
+		// Result of a request sent before the breaker tripped.
+		return
+	}
+
+	if len(b.outcomes) != cfg.Window {
+		b.outcomes = make([]bool, max(cfg.Window, 1))
+		b.next, b.filled = 0, 0
+	}
+	b.outcomes[b.next] = failed
+	b.next = (b.next + 1) % len(b.outcomes)
+	b.filled = min(b.filled+1, len(b.outcomes))
+
+	if failed {
+		b.consecutive++
+	} else {
+		b.consecutive = 0
+	}
+
+	if b.consecutive >= cfg.ConsecutiveFailures ||
+		(b.filled >= cfg.MinRequests && b.errorRate() >= cfg.ErrorRate) {
+		b.trip(now)
+	}
+}
+
+// release frees the probe slot of a request whose outcome says nothing about
+// the upstream, e.g. one cancelled by its caller.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitHalfOpen {
+		b.probing = false
+	}
+}
+
+func (b *breaker) current() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *breaker) errorRate() float64 {
+	failures := 0
+	for i := 0; i < b.filled; i++ {
+		if b.outcomes[i] {
+			failures++
+		}
+	}
+	return float64(failures) / float64(b.filled)
+}
+
+func (b *breaker) trip(now time.Time) {
+	b.openedAt = now
+	b.reset()
+	b.setState(CircuitOpen)
+}
+
+func (b *breaker) reset() {
+	b.consecutive = 0
+	b.next, b.filled = 0, 0
+}
+
+func (b *breaker) setState(state CircuitState) {
+	if b.state == state {
+		return
+	}
+	log.Warn().
+		Str("provider", b.provider).
+		Str("from", string(b.state)).
+		Str("to", string(state)).
+		Msg("Circuit breaker state changed")
+	b.state = state
+}
@@ -1,6 +1,17 @@
 package client
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
+
+// Fetcher resolves person attributes by first name. A nil value with a nil
+// error means the upstream has no data for the name.
+type Fetcher interface {
+	FetchAge(ctx context.Context, name string) (*AgeResult, error)
+	FetchGender(ctx context.Context, name string) (*GenderResult, error)
+	FetchNationality(ctx context.Context, name string) (*NationalityResult, error)
+}
 
 // APIClient provides methods to call external enrichment services.
 type APIClient struct {
@@ -8,6 +19,13 @@ type APIClient struct {
 	GenderURL      string
 	NationalityURL string
 	HTTPClient     *http.Client
+	// Retry holds retry policies per provider (ProviderAge, ...). Providers
+	// without a policy use DefaultRetryPolicy.
+	Retry map[string]RetryPolicy
+	// Breaker configures the per-provider circuit breakers.
+	Breaker BreakerConfig
+
+	providers map[string]*provider
 }
 
 func NewAPIClient(ageURL, genderURL, natURL string) *APIClient {
@@ -16,5 +34,11 @@ func NewAPIClient(ageURL, genderURL, natURL string) *APIClient {
 		GenderURL:      genderURL,
 		NationalityURL: natURL,
 		HTTPClient:     http.DefaultClient,
+		Breaker:        DefaultBreakerConfig,
+		providers: map[string]*provider{
+			ProviderAge:         newProvider(ProviderAge),
+			ProviderGender:      newProvider(ProviderGender),
+			ProviderNationality: newProvider(ProviderNationality),
+		},
 	}
 }
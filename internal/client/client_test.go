@@ -0,0 +1,133 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/k1lls3x/person-service/internal/client"
+	"github.com/k1lls3x/person-service/internal/fakeenrich"
+)
+
+// newClient returns a client of a fake upstream with fast retries.
+func newClient(t *testing.T) (*fakeenrich.Server, *client.APIClient) {
+	t.Helper()
+	fake, srv := fakeenrich.NewTestServer(fakeenrich.Config{}, nil)
+	t.Cleanup(srv.Close)
+
+	api := client.NewAPIClient(srv.URL+"/age", srv.URL+"/gender", srv.URL+"/nationality")
+	api.Retry = map[string]client.RetryPolicy{
+		client.ProviderAge: {MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+	}
+	return fake, api
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	fake, api := newClient(t)
+	fake.Script(fakeenrich.APIAge, fakeenrich.Response{Status: http.StatusServiceUnavailable})
+
+	res, err := api.FetchAge(context.Background(), "Ivan")
+	if err != nil {
+		t.Fatalf("FetchAge: %v", err)
+	}
+	if res == nil || res.Age != 36 {
+		t.Errorf("FetchAge(Ivan) = %+v, want the sample age 36", res)
+	}
+	if n := fake.Stats().Requests[fakeenrich.APIAge]; n != 2 {
+		t.Errorf("upstream got %d requests, want 2", n)
+	}
+	if stats := api.Stats()[client.ProviderAge]; stats.Retries != 1 || stats.Failures != 0 {
+		t.Errorf("stats = %+v, want 1 retry and no failures", stats)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	fake, api := newClient(t)
+	for range 3 {
+		fake.Script(fakeenrich.APIAge, fakeenrich.Response{Status: http.StatusInternalServerError, Body: []byte(`{"error":"boom"}`)})
+	}
+
+	_, err := api.FetchAge(context.Background(), "Ivan")
+	if !errors.Is(err, client.ErrServerError) {
+		t.Fatalf("err = %v, want ErrServerError", err)
+	}
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError || apiErr.Message != "boom" {
+		t.Errorf("err = %#v, want APIError with status 500 and message boom", err)
+	}
+	if n := fake.Stats().Requests[fakeenrich.APIAge]; n != 3 {
+		t.Errorf("upstream got %d requests, want 3", n)
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	fake, api := newClient(t)
+	fake.Script(fakeenrich.APIAge, fakeenrich.Response{
+		Status:  http.StatusTooManyRequests,
+		Headers: map[string]string{"X-Rate-Limit-Reset": "30"},
+	})
+
+	// The limit resets later than the caller is willing to wait, so the
+	// request is not retried.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := api.FetchAge(ctx, "Ivan")
+	if !errors.Is(err, client.ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	if reset := apiErr.RateLimitReset.Sub(start); reset < 29*time.Second || reset > 31*time.Second {
+		t.Errorf("RateLimitReset is %v after the request, want about 30s", reset)
+	}
+	if n := fake.Stats().Requests[fakeenrich.APIAge]; n != 1 {
+		t.Errorf("upstream got %d requests, want 1", n)
+	}
+}
+
+func TestBadRequestIsNotRetried(t *testing.T) {
+	fake, api := newClient(t)
+	fake.Script(fakeenrich.APIAge, fakeenrich.Response{Status: http.StatusUnprocessableEntity, Body: []byte(`{"error":"Invalid 'name' parameter"}`)})
+
+	_, err := api.FetchAge(context.Background(), "Ivan")
+	if !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("err = %v, want ErrBadRequest", err)
+	}
+	if n := fake.Stats().Requests[fakeenrich.APIAge]; n != 1 {
+		t.Errorf("upstream got %d requests, want 1", n)
+	}
+}
+
+func TestBreakerOpensAfterFailures(t *testing.T) {
+	fake, api := newClient(t)
+	api.Retry[client.ProviderAge] = client.RetryPolicy{MaxAttempts: 1}
+	api.Breaker = client.BreakerConfig{ConsecutiveFailures: 3, ErrorRate: 1, Window: 10, MinRequests: 10, OpenTimeout: time.Minute}
+	for range 3 {
+		fake.Script(fakeenrich.APIAge, fakeenrich.Response{Status: http.StatusInternalServerError})
+	}
+
+	for i := range 3 {
+		if _, err := api.FetchAge(context.Background(), "Ivan"); !errors.Is(err, client.ErrServerError) {
+			t.Fatalf("request %d: err = %v, want ErrServerError", i+1, err)
+		}
+	}
+	if _, err := api.FetchAge(context.Background(), "Ivan"); !errors.Is(err, client.ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if n := fake.Stats().Requests[fakeenrich.APIAge]; n != 3 {
+		t.Errorf("upstream got %d requests, want 3: the open breaker must fail fast", n)
+	}
+	stats := api.Stats()[client.ProviderAge]
+	if stats.Circuit != client.CircuitOpen || stats.Rejected != 1 {
+		t.Errorf("stats = %+v, want an open circuit with 1 rejected request", stats)
+	}
+	// Other providers have their own breakers.
+	if _, err := api.FetchGender(context.Background(), "Ivan"); err != nil {
+		t.Errorf("FetchGender: %v", err)
+	}
+}
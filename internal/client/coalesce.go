@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// coalesceTimeout bounds a coalesced batch request. Within it the batch runs
+// until the latest deadline among its callers, so retries do not outlive
+// every caller.
+const coalesceTimeout = 10 * time.Second
+
+// Coalescer implements Fetcher on top of a BatchFetcher. Single-name calls
+// arriving within window of each other are grouped into one batch request of
+// up to MaxBatchSize names.
+type Coalescer struct {
+	age         *batcher[AgeResult]
+	gender      *batcher[GenderResult]
+	nationality *batcher[NationalityResult]
+}
+
+func NewCoalescer(api BatchFetcher, window time.Duration) *Coalescer {
+	return &Coalescer{
+		age:         newBatcher(ProviderAge, window, api.FetchAges),
+		gender:      newBatcher(ProviderGender, window, api.FetchGenders),
+		nationality: newBatcher(ProviderNationality, window, api.FetchNationalities),
+	}
+}
+
+func (c *Coalescer) FetchAge(ctx context.Context, name string) (*AgeResult, error) {
+	return c.age.get(ctx, name)
+}
+
+func (c *Coalescer) FetchGender(ctx context.Context, name string) (*GenderResult, error) {
+	return c.gender.get(ctx, name)
+}
+
+func (c *Coalescer) FetchNationality(ctx context.Context, name string) (*NationalityResult, error) {
+	return c.nationality.get(ctx, name)
+}
+
+type batchResult[T any] struct {
+	value *T
+	err   error
+}
+
+// batcher collects names for one attribute until the window expires or the
+// batch is full, then resolves them with a single fetch call.
+type batcher[T any] struct {
+	attribute string
+	window    time.Duration
+	fetch     func(ctx context.Context, names []string) (map[string]*T, error)
+
+	mu      sync.Mutex
+	pending *pendingBatch[T]
+	timer   *time.Timer
+}
+
+// pendingBatch is a batch being collected or in flight. Its context is
+// cancelled once every caller waiting for it has given up.
+type pendingBatch[T any] struct {
+	names    []string
+	waiters  map[string][]chan batchResult[T]
+	deadline time.Time
+	ctx      context.Context
+	cancel   context.CancelFunc
+	// active counts callers still waiting for the result; guarded by
+	// batcher.mu.
+	active int
+}
+
+func newBatcher[T any](attribute string, window time.Duration, fetch func(ctx context.Context, names []string) (map[string]*T, error)) *batcher[T] {
+	return &batcher[T]{
+		attribute: attribute,
+		window:    window,
+		fetch:     fetch,
+	}
+}
+
+func (b *batcher[T]) get(ctx context.Context, name string) (*T, error) {
+	ch := make(chan batchResult[T], 1)
+
+	b.mu.Lock()
+	batch := b.pending
+	if batch == nil {
+		batchCtx, cancel := context.WithCancel(context.Background())
+		batch = &pendingBatch[T]{
+			waiters: make(map[string][]chan batchResult[T]),
+			ctx:     batchCtx,
+			cancel:  cancel,
+		}
+		b.pending = batch
+	}
+	if _, ok := batch.waiters[name]; !ok {
+		batch.names = append(batch.names, name)
+	}
+	batch.waiters[name] = append(batch.waiters[name], ch)
+	batch.active++
+	limit := time.Now().Add(coalesceTimeout)
+	deadline, ok := ctx.Deadline()
+	if !ok || deadline.After(limit) {
+		deadline = limit
+	}
+	if deadline.After(batch.deadline) {
+		batch.deadline = deadline
+	}
+	if len(batch.names) >= MaxBatchSize {
+		b.flushLocked()
+	} else if b.timer == nil {
+		b.timer = time.AfterFunc(b.window, b.flush)
+	}
+	b.mu.Unlock()
+
+	select {
+	case res := <-ch:
+		return res.value, res.err
+	case <-ctx.Done():
+		b.leave(batch)
+		return nil, ctx.Err()
+	}
+}
+
+// leave records that a caller stopped waiting for batch. When no callers
+// are left the batch is cancelled, or dropped if it has not been sent yet.
+func (b *batcher[T]) leave(batch *pendingBatch[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	batch.active--
+	if batch.active > 0 {
+		return
+	}
+	batch.cancel()
+	if b.pending == batch {
+		b.pending = nil
+		if b.timer != nil {
+			b.timer.Stop()
+			b.timer = nil
+		}
+	}
+}
+
+func (b *batcher[T]) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+}
+
+func (b *batcher[T]) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if b.pending == nil {
+		return
+	}
+	batch := b.pending
+	b.pending = nil
+	go b.run(batch)
+}
+
+func (b *batcher[T]) run(batch *pendingBatch[T]) {
+	ctx, cancel := context.WithDeadline(batch.ctx, batch.deadline)
+	defer cancel()
+	defer batch.cancel()
+
+	log.Debug().Str("attribute", b.attribute).Int("names", len(batch.names)).Msg("Sending coalesced batch")
+
+	values, err := b.fetch(ctx, batch.names)
+	for name, chans := range batch.waiters {
+		res := batchResult[T]{value: values[name], err: err}
+		for _, ch := range chans {
+			ch <- res
+		}
+	}
+}
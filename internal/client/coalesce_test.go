@@ -0,0 +1,69 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/k1lls3x/person-service/internal/client"
+	"github.com/k1lls3x/person-service/internal/fakeenrich"
+)
+
+// failingCoalescer returns a coalescer over an upstream that always answers
+// 500, with retries frequent enough to notice requests outliving callers.
+func failingCoalescer(t *testing.T) (*fakeenrich.Server, *client.Coalescer) {
+	t.Helper()
+	fake, srv := fakeenrich.NewTestServer(fakeenrich.Config{ErrorRate: 1}, nil)
+	t.Cleanup(srv.Close)
+
+	api := client.NewAPIClient(srv.URL+"/age", srv.URL+"/gender", srv.URL+"/nationality")
+	api.Retry = map[string]client.RetryPolicy{
+		client.ProviderAge: {MaxAttempts: 1000, BaseDelay: 20 * time.Millisecond, MaxDelay: 20 * time.Millisecond},
+	}
+	api.Breaker = client.BreakerConfig{ConsecutiveFailures: 1000, ErrorRate: 1, Window: 1000, MinRequests: 1000, OpenTimeout: time.Second}
+	return fake, client.NewCoalescer(api, 10*time.Millisecond)
+}
+
+// assertRequestsStop checks that no upstream requests are sent once every
+// caller has given up.
+func assertRequestsStop(t *testing.T, fake *fakeenrich.Server) {
+	t.Helper()
+	time.Sleep(50 * time.Millisecond)
+	before := fake.Stats().Requests[fakeenrich.APIAge]
+	time.Sleep(200 * time.Millisecond)
+	if after := fake.Stats().Requests[fakeenrich.APIAge]; after != before {
+		t.Fatalf("upstream requests continued after callers left: %d -> %d", before, after)
+	}
+}
+
+func TestCoalescerRespectsCallerDeadline(t *testing.T) {
+	fake, c := failingCoalescer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	if _, err := c.FetchAge(ctx, "Ivan"); err == nil {
+		t.Fatal("expected an error from a failing upstream")
+	}
+	assertRequestsStop(t, fake)
+}
+
+func TestCoalescerCancelsBatchWhenCallersLeave(t *testing.T) {
+	fake, c := failingCoalescer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 2)
+	for _, name := range []string{"Ivan", "Olga"} {
+		go func() {
+			_, err := c.FetchAge(ctx, name)
+			done <- err
+		}()
+	}
+	time.Sleep(150 * time.Millisecond)
+	cancel()
+	for range 2 {
+		if err := <-done; err == nil {
+			t.Fatal("expected an error after cancellation")
+		}
+	}
+	assertRequestsStop(t, fake)
+}
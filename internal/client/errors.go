@@ -0,0 +1,101 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Kinds of upstream errors. Use errors.Is to check the kind and errors.As
+// with *APIError to get the details.
+var (
+	ErrRateLimited      = errors.New("upstream rate limit reached")
+	ErrUnauthorized     = errors.New("upstream rejected credentials")
+	ErrBadRequest       = errors.New("upstream rejected the request")
+	ErrServerError      = errors.New("upstream server error")
+	ErrUnexpectedStatus = errors.New("unexpected upstream status")
+)
+
+// maxErrorBody limits how much of an error response is read.
+const maxErrorBody = 4 << 10
+
+// APIError describes a non-200 response of an upstream API.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	// Message is the "error" field of the response body, if any.
+	Message string
+	// RateLimitReset is when the rate limit resets; set for ErrRateLimited
+	// when the upstream reports it.
+	RateLimitReset time.Time
+
+	kind error
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s: %v (status %d)", e.Provider, e.kind, e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if !e.RateLimitReset.IsZero() {
+		msg += ", resets at " + e.RateLimitReset.UTC().Format(time.RFC3339)
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// checkResponse returns nil for 200 OK and an *APIError otherwise. The body of
+// an error response is consumed.
+func checkResponse(resp *http.Response, provider string) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	apiErr := &APIError{Provider: provider, StatusCode: resp.StatusCode}
+
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody)); err == nil {
+		if json.Unmarshal(body, &payload) == nil {
+			apiErr.Message = payload.Error
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		apiErr.kind = ErrRateLimited
+		if d := rateLimitDelay(resp); d > 0 {
+			apiErr.RateLimitReset = time.Now().Add(d)
+		}
+	case resp.StatusCode == http.StatusUnauthorized ||
+		resp.StatusCode == http.StatusPaymentRequired ||
+		resp.StatusCode == http.StatusForbidden:
+		apiErr.kind = ErrUnauthorized
+	case resp.StatusCode == http.StatusBadRequest ||
+		resp.StatusCode == http.StatusUnprocessableEntity:
+		apiErr.kind = ErrBadRequest
+	case resp.StatusCode >= 500:
+		apiErr.kind = ErrServerError
+	default:
+		apiErr.kind = ErrUnexpectedStatus
+	}
+	return apiErr
+}
+
+// rateLimitDelay returns how long until the rate limit resets: the larger of
+// X-Rate-Limit-Reset (seconds) and Retry-After.
+func rateLimitDelay(resp *http.Response) time.Duration {
+	var reset time.Duration
+	if secs, err := strconv.Atoi(resp.Header.Get("X-Rate-Limit-Reset")); err == nil && secs > 0 {
+		reset = time.Duration(secs) * time.Second
+	}
+	return max(reset, parseRetryAfter(resp.Header.Get("Retry-After")))
+}
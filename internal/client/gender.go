@@ -9,7 +9,25 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-func (c *APIClient) FetchGender(ctx context.Context, name string) (*string, error) {
+// GenderResult is the genderize answer for a name.
+type GenderResult struct {
+	// Gender is "male", "female" or GenderUnknown.
+	Gender      string  `json:"gender"`
+	Probability float64 `json:"probability"`
+	Count       int     `json:"count"`
+}
+
+type genderResponse struct {
+	Gender      *string `json:"gender"`
+	Probability float64 `json:"probability"`
+	Count       int     `json:"count"`
+}
+
+func (r genderResponse) result() *GenderResult {
+	return &GenderResult{Gender: genderOrUnknown(r.Gender), Probability: r.Probability, Count: r.Count}
+}
+
+func (c *APIClient) FetchGender(ctx context.Context, name string) (*GenderResult, error) {
 	apiURL := c.GenderURL + "?name=" + url.PathEscape(name)
 
 	log.Info().Str("name", name).Msg("Fetching gender from API")
@@ -21,23 +39,40 @@ func (c *APIClient) FetchGender(ctx context.Context, name string) (*string, erro
 		return nil, err
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req, ProviderGender)
 	if err != nil {
 		log.Error().Err(err).Str("url", apiURL).Msg("Failed to send request to external API")
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var result struct {
-		Gender string `json:"gender"`
+	if err := checkResponse(resp, ProviderGender); err != nil {
+		log.Error().Err(err).Str("url", apiURL).Msg("External API returned an error")
+		return nil, err
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	var response genderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		log.Error().Err(err).Str("url", apiURL).Msg("Failed to decode response from API")
 		return nil, err
 	}
 
-	log.Info().Str("name", name).Str("gender", result.Gender).Msg("Successfully fetched gender from API")
+	result := response.result()
+	log.Info().
+		Str("name", name).
+		Str("gender", result.Gender).
+		Float64("probability", result.Probability).
+		Msg("Successfully fetched gender from API")
+
+	return result, nil
+}
 
-	return ptrString(result.Gender), nil
+// GenderUnknown is reported when the upstream has no gender for a name.
+const GenderUnknown = "unknown"
+
+func genderOrUnknown(gender *string) string {
+	if gender == nil || *gender == "" {
+		return GenderUnknown
+	}
+	return *gender
 }
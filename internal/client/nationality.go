@@ -1,19 +1,49 @@
 package client
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"slices"
 
 	"github.com/rs/zerolog/log"
 )
 
-func ptrString(str string) *string {
-	return &str
+// CountryProbability is one candidate nationality.
+type CountryProbability struct {
+	CountryID   string  `json:"country_id"`
+	Probability float64 `json:"probability"`
 }
 
-func (c *APIClient) FetchNationality(ctx context.Context, name string) (*string, error) {
+// NationalityResult is the nationalize answer for a name: candidate
+// countries ordered by probability, most likely first.
+type NationalityResult struct {
+	Countries []CountryProbability `json:"countries"`
+}
+
+// Top returns the most likely country.
+func (r *NationalityResult) Top() CountryProbability {
+	return r.Countries[0]
+}
+
+type nationalityResponse struct {
+	Country []CountryProbability `json:"country"`
+}
+
+func (r nationalityResponse) result() *NationalityResult {
+	if len(r.Country) == 0 {
+		return nil
+	}
+	countries := slices.Clone(r.Country)
+	slices.SortStableFunc(countries, func(a, b CountryProbability) int {
+		return cmp.Compare(b.Probability, a.Probability)
+	})
+	return &NationalityResult{Countries: countries}
+}
+
+func (c *APIClient) FetchNationality(ctx context.Context, name string) (*NationalityResult, error) {
 	apiURL := c.NationalityURL + "?name=" + url.PathEscape(name)
 
 	log.Info().Str("name", name).Msg("Fetching nationality from API")
@@ -25,37 +55,35 @@ func (c *APIClient) FetchNationality(ctx context.Context, name string) (*string,
 		return nil, err
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req, ProviderNationality)
 	if err != nil {
 		log.Error().Err(err).Str("url", apiURL).Msg("Failed to send request to external API")
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var result struct {
-		Country []struct {
-			CountryID   string  `json:"country_id"`
-			Probability float64 `json:"probability"`
-		} `json:"country"`
+	if err := checkResponse(resp, ProviderNationality); err != nil {
+		log.Error().Err(err).Str("url", apiURL).Msg("External API returned an error")
+		return nil, err
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	var response nationalityResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		log.Error().Err(err).Str("url", apiURL).Msg("Failed to decode response from API")
 		return nil, err
 	}
 
-	if len(result.Country) == 0 {
+	result := response.result()
+	if result == nil {
 		log.Info().Str("name", name).Msg("No nationality data found")
 		return nil, nil
 	}
 
-	nationality := result.Country[0].CountryID
-
 	log.Info().
 		Str("name", name).
-		Str("nationality", nationality).
-		Float64("probability", result.Country[0].Probability).
+		Str("nationality", result.Top().CountryID).
+		Float64("probability", result.Top().Probability).
 		Msg("Successfully fetched nationality from API")
 
-	return ptrString(nationality), nil
+	return result, nil
 }
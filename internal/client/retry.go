@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Provider names used for retry policies and statistics.
+const (
+	ProviderAge         = "age"
+	ProviderGender      = "gender"
+	ProviderNationality = "nationality"
+)
+
+// RetryPolicy controls how failed upstream requests are retried. Transport
+// errors, 429 and 5xx responses are retried; other responses are returned as is.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the second attempt; it doubles with every
+	// further attempt up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy is used for providers without an explicit policy.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}
+
+// backoff returns the delay before the attempt following attempt, with equal
+// jitter: half of the exponential delay is fixed, the other half is random.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << min(attempt-1, 30)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// ProviderStats holds request counters of one provider since start and the
+// current state of its circuit breaker.
+type ProviderStats struct {
+	Requests int64 `json:"requests"`
+	Retries  int64 `json:"retries"`
+	// Failures counts requests that still failed after all attempts.
+	Failures int64 `json:"failures"`
+	// Rejected counts requests failed fast by the open circuit breaker.
+	Rejected int64        `json:"rejected"`
+	Circuit  CircuitState `json:"circuit"`
+}
+
+type provider struct {
+	requests atomic.Int64
+	retries  atomic.Int64
+	failures atomic.Int64
+	rejected atomic.Int64
+	breaker  *breaker
+}
+
+func newProvider(name string) *provider {
+	return &provider{breaker: newBreaker(name)}
+}
+
+func (c *APIClient) retryPolicy(provider string) RetryPolicy {
+	if p, ok := c.Retry[provider]; ok && p.MaxAttempts > 0 {
+		return p
+	}
+	return DefaultRetryPolicy
+}
+
+// Stats returns request counters and circuit breaker state per provider.
+func (c *APIClient) Stats() map[string]ProviderStats {
+	stats := make(map[string]ProviderStats, len(c.providers))
+	for name, p := range c.providers {
+		stats[name] = ProviderStats{
+			Requests: p.requests.Load(),
+			Retries:  p.retries.Load(),
+			Failures: p.failures.Load(),
+			Rejected: p.rejected.Load(),
+			Circuit:  p.breaker.current(),
+		}
+	}
+	return stats
+}
+
+// do sends req, retrying according to the provider's policy. A retry is not
+// attempted if its delay would not fit into the request context deadline.
+// Every attempt passes through the provider's circuit breaker.
+func (c *APIClient) do(req *http.Request, provider string) (*http.Response, error) {
+	ctx := req.Context()
+	policy := c.retryPolicy(provider)
+	prov := c.providers[provider]
+	prov.requests.Add(1)
+
+	for attempt := 1; ; attempt++ {
+		if !prov.breaker.allow(c.Breaker, time.Now()) {
+			prov.rejected.Add(1)
+			return nil, fmt.Errorf("%s: %w", provider, ErrCircuitOpen)
+		}
+
+		resp, err := c.HTTPClient.Do(req.Clone(ctx))
+
+		retryAfter, retryable := retryable(ctx, resp, err)
+		if err != nil && !retryable {
+			prov.breaker.release()
+		} else {
+			prov.breaker.record(c.Breaker, retryable, time.Now())
+		}
+		if !retryable {
+			return resp, err
+		}
+
+		delay := max(policy.backoff(attempt), retryAfter)
+		if attempt >= policy.MaxAttempts || !fitsDeadline(ctx, delay) {
+			prov.failures.Add(1)
+			log.Error().
+				Err(err).
+				Str("provider", provider).
+				Int("attempts", attempt).
+				Int("status", statusCode(resp)).
+				Msg("Upstream request failed, giving up")
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		prov.retries.Add(1)
+		log.Warn().
+			Err(err).
+			Str("provider", provider).
+			Int("attempt", attempt).
+			Int("status", statusCode(resp)).
+			Dur("retry_in", delay).
+			Msg("Upstream request failed, retrying")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether the outcome of a request is worth retrying and
+// the minimum delay requested by the server via Retry-After.
+func retryable(ctx context.Context, resp *http.Response, err error) (time.Duration, bool) {
+	if err != nil {
+		// A request cancelled by its caller is not worth retrying.
+		return 0, ctx.Err() == nil && !errors.Is(err, context.Canceled)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return rateLimitDelay(resp), true
+	}
+	if resp.StatusCode >= 500 {
+		return parseRetryAfter(resp.Header.Get("Retry-After")), true
+	}
+	return 0, false
+}
+
+// parseRetryAfter understands both forms of Retry-After: delay in seconds and
+// an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+func fitsDeadline(ctx context.Context, delay time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > delay
+}
+
+func statusCode(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
@@ -0,0 +1,261 @@
+// Package dataset отвечает на запросы обогащения по локальному справочнику
+// имён без обращения к внешним API — для окружений, где они недоступны.
+// Справочник загружается при запуске из CSV или JSON; без файла
+// используется встроенный пример (см. Sample).
+package dataset
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/k1lls3x/person-service/internal/client"
+)
+
+//go:embed sample.csv
+var sampleCSV string
+
+// Record — статистика по одному имени. Незаполненное поле означает, что
+// данных по атрибуту нет.
+type Record struct {
+	Name              string                      `json:"name"`
+	Age               *int                        `json:"age,omitempty"`
+	AgeCount          int                         `json:"age_count,omitempty"`
+	Gender            *string                     `json:"gender,omitempty"`
+	GenderProbability float64                     `json:"gender_probability,omitempty"`
+	GenderCount       int                         `json:"gender_count,omitempty"`
+	Nationalities     []client.CountryProbability `json:"nationalities,omitempty"`
+}
+
+// Dataset реализует client.Fetcher поверх справочника в памяти. Имена
+// сравниваются без учёта регистра и пробелов по краям.
+type Dataset struct {
+	records map[string]Record
+}
+
+// New строит справочник из записей. При повторе имени побеждает последняя
+// запись.
+func New(records []Record) *Dataset {
+	d := &Dataset{records: make(map[string]Record, len(records))}
+	for _, r := range records {
+		r.Nationalities = slices.Clone(r.Nationalities)
+		slices.SortStableFunc(r.Nationalities, func(a, b client.CountryProbability) int {
+			return cmp.Compare(b.Probability, a.Probability)
+		})
+		d.records[normalize(r.Name)] = r
+	}
+	return d
+}
+
+// Sample возвращает встроенный пример справочника.
+func Sample() *Dataset {
+	records, err := ParseCSV(strings.NewReader(sampleCSV))
+	if err != nil {
+		panic(fmt.Sprintf("dataset: invalid built-in sample: %v", err))
+	}
+	return New(records)
+}
+
+// Load читает справочник из файла; формат определяется расширением
+// (.csv или .json).
+func Load(path string) (*Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		records, err = ParseCSV(f)
+	case ".json":
+		records, err = ParseJSON(f)
+	default:
+		return nil, fmt.Errorf("dataset %s: unsupported format %q, expected .csv or .json", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dataset %s: %w", path, err)
+	}
+	return New(records), nil
+}
+
+// ParseJSON читает массив записей Record.
+func ParseJSON(r io.Reader) ([]Record, error) {
+	var records []Record
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, err
+	}
+	for i, rec := range records {
+		if strings.TrimSpace(rec.Name) == "" {
+			return nil, fmt.Errorf("record %d: name is required", i+1)
+		}
+		if rec.Gender != nil {
+			if err := validateGender(*rec.Gender); err != nil {
+				return nil, fmt.Errorf("record %d: %w", i+1, err)
+			}
+		}
+	}
+	return records, nil
+}
+
+// ParseCSV читает CSV с заголовком. Обязательна колонка name, остальные —
+// age, age_count, gender, gender_probability, gender_count и nationalities —
+// могут отсутствовать или быть пустыми. nationalities перечисляет страны
+// через точку с запятой: "RU:0.6;UA:0.2".
+func ParseCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("name column is required")
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		rec, err := parseRow(columns, row)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+}
+
+func parseRow(columns map[string]int, row []string) (Record, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	rec := Record{Name: field("name")}
+	if rec.Name == "" {
+		return rec, errors.New("name is required")
+	}
+
+	var err error
+	if s := field("age"); s != "" {
+		age, err := strconv.Atoi(s)
+		if err != nil {
+			return rec, fmt.Errorf("invalid age %q", s)
+		}
+		rec.Age = &age
+	}
+	if rec.AgeCount, err = parseInt(field("age_count")); err != nil {
+		return rec, fmt.Errorf("invalid age_count: %w", err)
+	}
+	if s := field("gender"); s != "" {
+		if err := validateGender(s); err != nil {
+			return rec, err
+		}
+		rec.Gender = &s
+	}
+	if s := field("gender_probability"); s != "" {
+		if rec.GenderProbability, err = parseProbability(s); err != nil {
+			return rec, fmt.Errorf("invalid gender_probability: %w", err)
+		}
+	}
+	if rec.GenderCount, err = parseInt(field("gender_count")); err != nil {
+		return rec, fmt.Errorf("invalid gender_count: %w", err)
+	}
+	if s := field("nationalities"); s != "" {
+		for _, item := range strings.Split(s, ";") {
+			country, prob, ok := strings.Cut(strings.TrimSpace(item), ":")
+			if !ok {
+				return rec, fmt.Errorf("invalid nationality %q, expected COUNTRY:probability", item)
+			}
+			p, err := parseProbability(prob)
+			if err != nil {
+				return rec, fmt.Errorf("invalid nationality %q: %w", item, err)
+			}
+			rec.Nationalities = append(rec.Nationalities, client.CountryProbability{
+				CountryID:   strings.ToUpper(strings.TrimSpace(country)),
+				Probability: p,
+			})
+		}
+	}
+	return rec, nil
+}
+
+// validateGender принимает только значения, допустимые в persons.gender.
+func validateGender(s string) error {
+	if s != "male" && s != "female" {
+		return fmt.Errorf("invalid gender %q, expected male or female", s)
+	}
+	return nil
+}
+
+func parseInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func parseProbability(s string) (float64, error) {
+	p, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || p < 0 || p > 1 {
+		return 0, fmt.Errorf("%q is not a number between 0 and 1", s)
+	}
+	return p, nil
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Len возвращает число имён в справочнике.
+func (d *Dataset) Len() int {
+	return len(d.records)
+}
+
+func (d *Dataset) FetchAge(_ context.Context, name string) (*client.AgeResult, error) {
+	rec, ok := d.records[normalize(name)]
+	if !ok || rec.Age == nil {
+		return nil, nil
+	}
+	return &client.AgeResult{Age: *rec.Age, Count: rec.AgeCount}, nil
+}
+
+func (d *Dataset) FetchGender(_ context.Context, name string) (*client.GenderResult, error) {
+	rec, ok := d.records[normalize(name)]
+	if !ok || rec.Gender == nil {
+		return nil, nil
+	}
+	return &client.GenderResult{Gender: *rec.Gender, Probability: rec.GenderProbability, Count: rec.GenderCount}, nil
+}
+
+func (d *Dataset) FetchNationality(_ context.Context, name string) (*client.NationalityResult, error) {
+	rec, ok := d.records[normalize(name)]
+	if !ok || len(rec.Nationalities) == 0 {
+		return nil, nil
+	}
+	return &client.NationalityResult{Countries: slices.Clone(rec.Nationalities)}, nil
+}
@@ -0,0 +1,84 @@
+package dataset
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	records, err := ParseCSV(strings.NewReader(`name,age,age_count,gender,gender_probability,nationalities
+Ivan,36,100,male,0.99,ua:0.2;RU:0.6
+Kim,,,,,
+`))
+	if err != nil {
+		t.Fatalf("ParseCSV: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+
+	d := New(records)
+	age, _ := d.FetchAge(context.Background(), " IVAN ")
+	if age == nil || age.Age != 36 || age.Count != 100 {
+		t.Errorf("FetchAge(Ivan) = %+v, want age 36 from 100 samples", age)
+	}
+	nat, _ := d.FetchNationality(context.Background(), "Ivan")
+	if nat == nil || len(nat.Countries) != 2 || nat.Top().CountryID != "RU" || nat.Countries[1].CountryID != "UA" {
+		t.Errorf("FetchNationality(Ivan) = %+v, want RU then UA", nat)
+	}
+	if g, _ := d.FetchGender(context.Background(), "Kim"); g != nil {
+		t.Errorf("FetchGender(Kim) = %+v, want no data", g)
+	}
+}
+
+func TestParseCSVRejectsInvalidRows(t *testing.T) {
+	for name, input := range map[string]string{
+		"no name column":    "age\n30\n",
+		"empty name":        "name,age\n,30\n",
+		"bad age":           "name,age\nIvan,old\n",
+		"bad gender":        "name,gender\nIvan,Male\n",
+		"short gender":      "name,gender\nIvan,m\n",
+		"probability > 1":   "name,gender,gender_probability\nIvan,male,1.5\n",
+		"bad nationalities": "name,nationalities\nIvan,RU\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseCSV(strings.NewReader(input)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	records, err := ParseJSON(strings.NewReader(`[
+		{"name": "Olga", "gender": "female", "gender_probability": 1, "gender_count": 10}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseJSON: %v", err)
+	}
+	g, _ := New(records).FetchGender(context.Background(), "olga")
+	if g == nil || g.Gender != "female" || g.Count != 10 {
+		t.Errorf("FetchGender(olga) = %+v, want female from 10 samples", g)
+	}
+}
+
+func TestParseJSONRejectsInvalidRecords(t *testing.T) {
+	for name, input := range map[string]string{
+		"empty name": `[{"name": " "}]`,
+		"bad gender": `[{"name": "Ivan", "gender": "M"}]`,
+		"not array":  `{"name": "Ivan"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseJSON(strings.NewReader(input)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestSample(t *testing.T) {
+	if n := Sample().Len(); n == 0 {
+		t.Fatal("built-in sample is empty")
+	}
+}
@@ -0,0 +1,20 @@
+package entity
+
+// BatchMode определяет, как сохраняется пакет записей.
+type BatchMode string
+
+const (
+	// BatchModeAtomic вставляет все записи в одной транзакции: при любой
+	// ошибке не сохраняется ничего.
+	BatchModeAtomic BatchMode = "atomic"
+	// BatchModePerItem сохраняет каждую запись независимо.
+	BatchModePerItem BatchMode = "per_item"
+)
+
+// BatchItemResult — результат обработки одного элемента пакета.
+type BatchItemResult struct {
+	Index  int     `json:"index"`
+	Status int     `json:"status"`
+	Person *Person `json:"person,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
@@ -0,0 +1,148 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// AttributeState — результат обогащения одного атрибута.
+type AttributeState string
+
+const (
+	AttributeOK      AttributeState = "ok"      // значение получено
+	AttributeMissing AttributeState = "missing" // API ответил, но значения нет
+	AttributeError   AttributeState = "error"   // API недоступен или вернул ошибку
+)
+
+// EnrichmentState хранит состояние обогащения по каждому атрибуту. В БД
+// сохраняется как JSONB в колонке enrichment_state.
+type EnrichmentState struct {
+	Age         AttributeState `json:"age,omitempty"`
+	Gender      AttributeState `json:"gender,omitempty"`
+	Nationality AttributeState `json:"nationality,omitempty"`
+}
+
+// Failed возвращает атрибуты, которые не удалось обогатить из-за ошибок.
+func (s EnrichmentState) Failed() []string {
+	var failed []string
+	if s.Age == AttributeError {
+		failed = append(failed, "age")
+	}
+	if s.Gender == AttributeError {
+		failed = append(failed, "gender")
+	}
+	if s.Nationality == AttributeError {
+		failed = append(failed, "nationality")
+	}
+	return failed
+}
+
+// Status сводит состояние атрибутов к статусу записи: done, если ошибок нет,
+// failed, если не удалось ни одного из обогащавшихся атрибутов, иначе
+// partial. Пустое состояние означает, что атрибут не обогащался.
+func (s EnrichmentState) Status() EnrichmentStatus {
+	failed := len(s.Failed())
+	switch {
+	case failed == 0:
+		return EnrichmentDone
+	case failed == s.attempted():
+		return EnrichmentFailed
+	default:
+		return EnrichmentPartial
+	}
+}
+
+func (s EnrichmentState) attempted() int {
+	n := 0
+	for _, state := range []AttributeState{s.Age, s.Gender, s.Nationality} {
+		if state != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func (s EnrichmentState) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+func (s *EnrichmentState) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = EnrichmentState{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("cannot scan %T into EnrichmentState", src)
+	}
+}
+
+// AttributeSource — откуда взято значение атрибута.
+type AttributeSource string
+
+const (
+	SourceEnriched AttributeSource = "enriched" // получено обогащением
+	SourceManual   AttributeSource = "manual"   // задано оператором, обогащение его не перезаписывает
+)
+
+// AttributeSources хранит источник значения каждого атрибута; у незаполненного
+// атрибута источник пуст. В БД сохраняется как JSONB в колонке
+// attribute_sources.
+type AttributeSources struct {
+	Age         AttributeSource `json:"age,omitempty"`
+	Gender      AttributeSource `json:"gender,omitempty"`
+	Nationality AttributeSource `json:"nationality,omitempty"`
+}
+
+func (s AttributeSources) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+func (s *AttributeSources) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = AttributeSources{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("cannot scan %T into AttributeSources", src)
+	}
+}
+
+// NationalityProbability — одна из возможных национальностей.
+type NationalityProbability struct {
+	CountryID   string  `json:"country_id"`
+	Probability float64 `json:"probability"`
+}
+
+// Nationalities — ранжированный список национальностей. В БД хранится как
+// JSONB в колонке nationalities, пустой список — как NULL.
+type Nationalities []NationalityProbability
+
+func (n Nationalities) Value() (driver.Value, error) {
+	if len(n) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(n)
+}
+
+func (n *Nationalities) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*n = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, n)
+	case string:
+		return json.Unmarshal([]byte(v), n)
+	default:
+		return fmt.Errorf("cannot scan %T into Nationalities", src)
+	}
+}
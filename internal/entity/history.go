@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ChangeSource — источник изменения записи, попадает в persons_history.
+type ChangeSource string
+
+const (
+	ChangeSourceAPI        ChangeSource = "api"
+	ChangeSourceEnrichment ChangeSource = "enrichment"
+	ChangeSourceImport     ChangeSource = "import"
+	ChangeSourceAdmin      ChangeSource = "admin"
+)
+
+// PersonHistoryEntry — одна запись журнала изменений. OldData и NewData
+// содержат строку persons до и после изменения в формате Person.
+type PersonHistoryEntry struct {
+	ID        int64           `db:"id" json:"id"`
+	PersonID  int             `db:"person_id" json:"person_id"`
+	Operation string          `db:"operation" json:"operation"` // insert, update, delete, restore, purge
+	Source    ChangeSource    `db:"source" json:"source"`
+	ChangedAt time.Time       `db:"changed_at" json:"changed_at"`
+	OldData   json.RawMessage `db:"old_data" json:"old_data,omitempty" swaggertype:"object"`
+	NewData   json.RawMessage `db:"new_data" json:"new_data,omitempty" swaggertype:"object"`
+}
@@ -0,0 +1,45 @@
+package entity
+
+import "encoding/json"
+
+// OptionalString различает три состояния поля в JSON Merge Patch (RFC 7396):
+// поле отсутствует (Set == false), явный null (Set == true, Value == nil)
+// и конкретное значение.
+type OptionalString struct {
+	Set   bool
+	Value *string
+}
+
+func (o *OptionalString) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	o.Value = &v
+	return nil
+}
+
+// OptionalInt — аналог OptionalString для целочисленных полей.
+type OptionalInt struct {
+	Set   bool
+	Value *int
+}
+
+func (o *OptionalInt) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+	var v int
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	o.Value = &v
+	return nil
+}
@@ -3,30 +3,97 @@ package entity
 import "time"
 
 type Person struct {
-	ID          int       `db:"id" json:"id"`
-	Name        string    `db:"name" json:"name" validate:"required"`
-	Surname     string    `db:"surname" json:"surname" validate:"required"`
-	Patronymic  *string   `db:"patronymic" json:"patronymic,omitempty"`
-	Age         *int      `db:"age" json:"age,omitempty"`
-	Gender      *string   `db:"gender" json:"gender,omitempty"`
-	Nationality *string   `db:"nationality" json:"nationality,omitempty"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   string    `db:"updated_at" json:"updated_at"`
+	ID          int        `db:"id" json:"id"`
+	Name        string     `db:"name" json:"name" validate:"required"`
+	Surname     string     `db:"surname" json:"surname" validate:"required"`
+	Patronymic  *string    `db:"patronymic" json:"patronymic,omitempty"`
+	Age         *int       `db:"age" json:"age,omitempty"`
+	Gender      *string    `db:"gender" json:"gender,omitempty"`
+	Nationality *string    `db:"nationality" json:"nationality,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   string     `db:"updated_at" json:"updated_at"`
+	Version     int        `db:"version" json:"version"`       // используется как ETag для optimistic concurrency
+	Score       *float64   `db:"score" json:"score,omitempty"` // релевантность при поиске по q
+	DeletedAt   *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+
+	EnrichmentStatus EnrichmentStatus `db:"enrichment_status" json:"enrichment_status"`
+	EnrichmentError  *string          `db:"enrichment_error" json:"enrichment_error,omitempty"`
+	// EnrichmentState показывает, какие атрибуты не удалось обогатить и их
+	// стоит запросить повторно.
+	EnrichmentState EnrichmentState `db:"enrichment_state" json:"enrichment_state"`
+	// EnrichedAt — время последнего обогащения, в котором получен хотя бы
+	// один атрибут.
+	EnrichedAt *time.Time `db:"enriched_at" json:"enriched_at,omitempty"`
+
+	// Достоверность обогащённых значений. Для значений, заданных вручную,
+	// не заполняется.
+	GenderProbability *float64      `db:"gender_probability" json:"gender_probability,omitempty"`
+	AgeSampleCount    *int          `db:"age_sample_count" json:"age_sample_count,omitempty"`
+	Nationalities     Nationalities `db:"nationalities" json:"nationalities,omitempty"` // по убыванию вероятности
+
+	// AttributeSources показывает, какие атрибуты заданы вручную.
+	AttributeSources AttributeSources `db:"attribute_sources" json:"attribute_sources"`
 }
 
+// EnrichmentStatus — состояние обогащения записи внешними API.
+type EnrichmentStatus string
+
+const (
+	EnrichmentPending EnrichmentStatus = "pending" // ожидает фонового обогащения
+	EnrichmentDone    EnrichmentStatus = "done"
+	EnrichmentPartial EnrichmentStatus = "partial" // часть атрибутов не получена, см. enrichment_state
+	EnrichmentFailed  EnrichmentStatus = "failed"  // не получен ни один атрибут, см. enrichment_error
+)
+
+// EnrichmentPolicy определяет, что делать при частичном сбое обогащения.
+type EnrichmentPolicy string
+
+const (
+	// EnrichmentPolicyPartial сохраняет полученные атрибуты, а несостоявшиеся
+	// помечает в enrichment_state.
+	EnrichmentPolicyPartial EnrichmentPolicy = "partial"
+	// EnrichmentPolicyStrict отклоняет операцию при любой ошибке обогащения.
+	EnrichmentPolicyStrict EnrichmentPolicy = "strict"
+)
+
+// CreatePersonInput — данные новой записи. Переданные возраст, пол и
+// национальность сохраняются как ручные и не обогащаются.
 type CreatePersonInput struct {
-	Name       string  `json:"name" validate:"required"`
-	Surname    string  `json:"surname" validate:"required"`
-	Patronymic *string `json:"patronymic,omitempty"`
+	Name        string  `json:"name" validate:"required"`
+	Surname     string  `json:"surname" validate:"required"`
+	Patronymic  *string `json:"patronymic,omitempty"`
+	Age         *int    `json:"age,omitempty"`
+	Gender      *string `json:"gender,omitempty"`
+	Nationality *string `json:"nationality,omitempty"`
 }
 
+// UpdatePersonInput заменяет запись целиком: непереданные возраст, пол и
+// национальность заново заполняются обогащением.
 type UpdatePersonInput struct {
-	Name       string  `json:"name"`
-	Surname    string  `json:"surname"`
-	Patronymic *string `json:"patronymic"`
+	Name        string  `json:"name"`
+	Surname     string  `json:"surname"`
+	Patronymic  *string `json:"patronymic"`
+	Age         *int    `json:"age"`
+	Gender      *string `json:"gender"`
+	Nationality *string `json:"nationality"`
+}
+
+// PatchPersonInput описывает тело PATCH-запроса в формате JSON Merge Patch:
+// изменяются только переданные поля, явный null очищает колонку. Переданные
+// возраст, пол и национальность становятся ручными, null снимает ручное
+// значение, и атрибут снова заполняется обогащением.
+type PatchPersonInput struct {
+	Name        OptionalString `json:"name" swaggertype:"string"`
+	Surname     OptionalString `json:"surname" swaggertype:"string"`
+	Patronymic  OptionalString `json:"patronymic" swaggertype:"string"`
+	Age         OptionalInt    `json:"age" swaggertype:"integer"`
+	Gender      OptionalString `json:"gender" swaggertype:"string"`
+	Nationality OptionalString `json:"nationality" swaggertype:"string"`
 }
 
 type PersonFilter struct {
+	// Query — нечёткий поиск по ФИО (pg_trgm) с учётом транслитерации.
+	Query       *string `form:"q" json:"q,omitempty"`
 	Name        *string `form:"name" json:"name,omitempty"`
 	Surname     *string `form:"surname" json:"surname,omitempty"`
 	Patronymic  *string `form:"patronymic" json:"patronymic,omitempty"`
@@ -36,4 +103,80 @@ type PersonFilter struct {
 	MaxAge      *int    `form:"max_age" json:"max_age,omitempty"`
 	Page        int     `form:"page" json:"page" validate:"gte=1"`                   // по умолчанию 1
 	PageSize    int     `form:"page_size" json:"page_size" validate:"gte=1,lte=100"` // ограничение на размер страницы
+	// Cursor включает keyset-пагинацию по (created_at, id). Пустая строка —
+	// первая страница, nil — обычная пагинация через page/pageSize.
+	Cursor *string `form:"cursor" json:"cursor,omitempty"`
+	// Count задаёт способ подсчёта total, по умолчанию CountExact.
+	Count CountMode `form:"count" json:"count,omitempty"`
+	// IncludeDeleted включает в выборку мягко удалённые записи.
+	IncludeDeleted bool `form:"include_deleted" json:"include_deleted,omitempty"`
+	// Sort — порядок сортировки; пустой означает created_at DESC.
+	Sort []SortField `form:"sort" json:"sort,omitempty"`
+	// EnrichmentStatus отбирает записи с указанным статусом обогащения.
+	EnrichmentStatus *EnrichmentStatus `form:"enrichment_status" json:"enrichment_status,omitempty"`
+	// MissingAttributes отбирает записи, у которых не заполнен хотя бы один
+	// из обогащаемых атрибутов.
+	MissingAttributes bool `form:"missing" json:"missing,omitempty"`
+	// Пороги достоверности: записи с меньшей вероятностью пола или
+	// национальности либо с меньшим числом наблюдений возраста (в том числе
+	// без этих данных) исключаются.
+	MinGenderProbability      *float64 `form:"min_gender_probability" json:"min_gender_probability,omitempty"`
+	MinNationalityProbability *float64 `form:"min_nationality_probability" json:"min_nationality_probability,omitempty"`
+	MinAgeSampleCount         *int     `form:"min_age_sample_count" json:"min_age_sample_count,omitempty"`
+}
+
+// SortField — одно поле сортировки списка.
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
+// PersonSortColumns — белый список полей сортировки: имя параметра -> колонка.
+var PersonSortColumns = map[string]string{
+	"id":          "id",
+	"name":        "name",
+	"surname":     "surname",
+	"patronymic":  "patronymic",
+	"age":         "age",
+	"gender":      "gender",
+	"nationality": "nationality",
+	"created_at":  "created_at",
+	"updated_at":  "updated_at",
+}
+
+// CountMode определяет, как считается общее количество записей в списке.
+type CountMode string
+
+const (
+	CountExact     CountMode = "exact"     // COUNT(*) по фильтру
+	CountEstimated CountMode = "estimated" // оценка планировщика PostgreSQL
+	CountNone      CountMode = "none"      // без подсчёта
+)
+
+// PersonList — страница результатов GetPersons.
+type PersonList struct {
+	Items      []Person  `json:"items"`
+	Page       int       `json:"page,omitempty"` // не заполняется при пагинации курсором
+	PageSize   int       `json:"page_size"`
+	Total      *int64    `json:"total"` // null при count=none
+	Count      CountMode `json:"count"`
+	HasMore    bool      `json:"has_more"`
+	NextCursor *string   `json:"next_cursor,omitempty"`
+	Links      PageLinks `json:"links"`
+}
+
+// PageLinks содержит ссылки на соседние страницы; null, если страницы нет.
+type PageLinks struct {
+	Next *string `json:"next"`
+	Prev *string `json:"prev"`
+}
+
+// PurgeResult — ответ административной очистки удалённых записей.
+type PurgeResult struct {
+	Purged int64 `json:"purged"`
+}
+
+// EnrichQueued — ответ на массовое повторное обогащение.
+type EnrichQueued struct {
+	Queued int64 `json:"queued"`
 }
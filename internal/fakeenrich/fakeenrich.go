@@ -0,0 +1,376 @@
+// Package fakeenrich — имитация внешних API agify, genderize и nationalize
+// для локальной разработки и тестов. Сервер отвечает в форматах настоящих
+// API (одно имя через name, до 10 имён через name[]), берёт данные из
+// справочника dataset и умеет воспроизводить сбои: задержки, ошибки 5xx,
+// лимит запросов с ответом 429 и заранее заданные ответы.
+//
+// Каждый API обслуживается по своему пути: /age, /gender и /nationality.
+// В тестах сервер поднимается через NewTestServer, отдельным процессом — через
+// cmd/fakeenrich.
+package fakeenrich
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/k1lls3x/person-service/internal/client"
+	"github.com/k1lls3x/person-service/internal/dataset"
+)
+
+// Имена имитируемых API; совпадают с путями, по которым они обслуживаются.
+const (
+	APIAge         = client.ProviderAge
+	APIGender      = client.ProviderGender
+	APINationality = client.ProviderNationality
+)
+
+// maxBatchSize — сколько имён настоящие API принимают в одном запросе.
+const maxBatchSize = client.MaxBatchSize
+
+// Config задаёт поведение сервера. Нулевое значение — быстрые ответы без
+// ошибок и без лимита.
+type Config struct {
+	// Latency — задержка перед каждым ответом.
+	Latency time.Duration
+	// Jitter — случайная добавка к задержке от 0 до Jitter.
+	Jitter time.Duration
+	// ErrorRate — доля запросов (0..1), на которые отвечает 500.
+	ErrorRate float64
+	// RateLimit — сколько запросов к одному API принимается за RateWindow;
+	// сверх лимита отвечает 429. 0 — без лимита.
+	RateLimit int
+	// RateWindow — окно лимита запросов (по умолчанию 1s).
+	RateWindow time.Duration
+	// Seed инициализирует генератор случайных ошибок и задержек, чтобы
+	// прогоны были воспроизводимы.
+	Seed int64
+}
+
+// Response — заранее заданный ответ. Body отправляется как есть; пустой Body
+// при статусе 200 (или без статуса) заменяется обычным ответом по
+// справочнику — так можно задать только задержку.
+type Response struct {
+	Status  int               `json:"status"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Delay заменяет задержку из Config для этого ответа. В JSON задаётся
+	// строкой в формате time.ParseDuration: "500ms".
+	Delay time.Duration `json:"delay,omitempty"`
+}
+
+func (r *Response) UnmarshalJSON(data []byte) error {
+	type plain Response
+	var raw struct {
+		plain
+		Delay string `json:"delay"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*r = Response(raw.plain)
+	if raw.Delay != "" {
+		d, err := time.ParseDuration(raw.Delay)
+		if err != nil {
+			return fmt.Errorf("invalid delay: %w", err)
+		}
+		r.Delay = d
+	}
+	return nil
+}
+
+// Stats — число запросов по каждому API и ответов со сбоями.
+type Stats struct {
+	Requests    map[string]int `json:"requests"`
+	Errors      int            `json:"errors"`
+	RateLimited int            `json:"rate_limited"`
+	Scripted    int            `json:"scripted"`
+}
+
+// Server — имитация внешних API. Безопасен для конкурентного использования.
+type Server struct {
+	cfg  Config
+	data *dataset.Dataset
+
+	mu          sync.Mutex
+	rng         *rand.Rand
+	scripts     map[string][]Response
+	windows     map[string]*rateWindow
+	requests    map[string]int
+	errors      int
+	rateLimited int
+	scripted    int
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// New создаёт сервер, отвечающий по справочнику data; nil — встроенный
+// пример dataset.Sample.
+func New(cfg Config, data *dataset.Dataset) *Server {
+	if cfg.RateWindow <= 0 {
+		cfg.RateWindow = time.Second
+	}
+	if data == nil {
+		data = dataset.Sample()
+	}
+	return &Server{
+		cfg:      cfg,
+		data:     data,
+		rng:      rand.New(rand.NewSource(cfg.Seed)),
+		scripts:  make(map[string][]Response),
+		windows:  make(map[string]*rateWindow),
+		requests: make(map[string]int),
+	}
+}
+
+// NewTestServer запускает сервер на httptest.Server. Адреса API для
+// client.NewAPIClient — URL + "/age", "/gender" и "/nationality".
+func NewTestServer(cfg Config, data *dataset.Dataset) (*Server, *httptest.Server) {
+	s := New(cfg, data)
+	return s, httptest.NewServer(s.Handler())
+}
+
+// Handler возвращает HTTP-обработчик API. Кроме самих API он обслуживает
+// служебные пути: POST /_script/{api} добавляет заранее заданные ответы
+// (JSON-массив Response), DELETE /_script сбрасывает их, GET /_stats
+// возвращает Stats.
+func (s *Server) Handler() http.Handler {
+	r := chi.NewRouter()
+	for _, api := range []string{APIAge, APIGender, APINationality} {
+		r.Get("/"+api, s.serveAPI(api))
+	}
+	r.Post("/_script/{api}", s.serveScript)
+	r.Delete("/_script", s.serveResetScripts)
+	r.Get("/_stats", s.serveStats)
+	return r
+}
+
+// Script ставит ответы в очередь API: следующие запросы к нему получат их по
+// порядку, после чего сервер вернётся к обычному поведению.
+func (s *Server) Script(api string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[api] = append(s.scripts[api], responses...)
+}
+
+// ResetScripts удаляет все не отправленные заранее заданные ответы.
+func (s *Server) ResetScripts() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts = make(map[string][]Response)
+}
+
+// Stats возвращает счётчики запросов с момента запуска.
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := make(map[string]int, len(s.requests))
+	for api, n := range s.requests {
+		requests[api] = n
+	}
+	return Stats{Requests: requests, Errors: s.errors, RateLimited: s.rateLimited, Scripted: s.scripted}
+}
+
+// outcome — решение о том, как ответить на запрос.
+type outcome struct {
+	delay      time.Duration
+	script     *Response
+	failed     bool
+	retryAfter time.Duration // > 0 — лимит исчерпан
+}
+
+func (s *Server) decide(api string, now time.Time) outcome {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[api]++
+	out := outcome{delay: s.cfg.Latency}
+	if s.cfg.Jitter > 0 {
+		out.delay += time.Duration(s.rng.Int63n(int64(s.cfg.Jitter) + 1))
+	}
+
+	if queue := s.scripts[api]; len(queue) > 0 {
+		out.script = &queue[0]
+		s.scripts[api] = queue[1:]
+		s.scripted++
+		if out.script.Delay > 0 {
+			out.delay = out.script.Delay
+		}
+		return out
+	}
+
+	if s.cfg.RateLimit > 0 {
+		w := s.windows[api]
+		if w == nil || now.Sub(w.start) >= s.cfg.RateWindow {
+			w = &rateWindow{start: now}
+			s.windows[api] = w
+		}
+		w.count++
+		if w.count > s.cfg.RateLimit {
+			s.rateLimited++
+			out.retryAfter = w.start.Add(s.cfg.RateWindow).Sub(now)
+			return out
+		}
+	}
+
+	if s.cfg.ErrorRate > 0 && s.rng.Float64() < s.cfg.ErrorRate {
+		s.errors++
+		out.failed = true
+	}
+	return out
+}
+
+func (s *Server) serveAPI(api string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		out := s.decide(api, time.Now())
+
+		if out.delay > 0 {
+			select {
+			case <-time.After(out.delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		switch {
+		case out.script != nil && (len(out.script.Body) > 0 || (out.script.Status != 0 && out.script.Status != http.StatusOK)):
+			for k, v := range out.script.Headers {
+				w.Header().Set(k, v)
+			}
+			status := out.script.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write(out.script.Body)
+			return
+		case out.retryAfter > 0:
+			// Настоящие API сообщают время до сброса лимита в секундах.
+			secs := int((out.retryAfter + time.Second - 1) / time.Second)
+			w.Header().Set("X-Rate-Limit-Limit", strconv.Itoa(s.cfg.RateLimit))
+			w.Header().Set("X-Rate-Limit-Remaining", "0")
+			w.Header().Set("X-Rate-Limit-Reset", strconv.Itoa(secs))
+			writeError(w, http.StatusTooManyRequests, "Request limit reached")
+			return
+		case out.failed:
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		s.answer(w, r, api)
+	}
+}
+
+// answer отвечает по справочнику: объектом на name и массивом на name[].
+func (s *Server) answer(w http.ResponseWriter, r *http.Request, api string) {
+	q := r.URL.Query()
+	if names, ok := q["name[]"]; ok {
+		if len(names) == 0 || len(names) > maxBatchSize {
+			writeError(w, http.StatusUnprocessableEntity, "Invalid 'name[]' parameter")
+			return
+		}
+		items := make([]any, len(names))
+		for i, name := range names {
+			items[i] = s.lookup(r, api, name)
+		}
+		writeJSON(w, http.StatusOK, items)
+		return
+	}
+
+	name := q.Get("name")
+	if name == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Missing 'name' parameter")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.lookup(r, api, name))
+}
+
+type ageBody struct {
+	Count int    `json:"count"`
+	Name  string `json:"name"`
+	Age   *int   `json:"age"`
+}
+
+type genderBody struct {
+	Count       int     `json:"count"`
+	Name        string  `json:"name"`
+	Gender      *string `json:"gender"`
+	Probability float64 `json:"probability"`
+}
+
+type nationalityBody struct {
+	Count   int                         `json:"count"`
+	Name    string                      `json:"name"`
+	Country []client.CountryProbability `json:"country"`
+}
+
+func (s *Server) lookup(r *http.Request, api, name string) any {
+	ctx := r.Context()
+	switch api {
+	case APIAge:
+		body := ageBody{Name: name}
+		if res, _ := s.data.FetchAge(ctx, name); res != nil {
+			body.Age, body.Count = &res.Age, res.Count
+		}
+		return body
+	case APIGender:
+		body := genderBody{Name: name}
+		if res, _ := s.data.FetchGender(ctx, name); res != nil {
+			body.Gender, body.Probability, body.Count = &res.Gender, res.Probability, res.Count
+		}
+		return body
+	default:
+		body := nationalityBody{Name: name, Country: []client.CountryProbability{}}
+		if res, _ := s.data.FetchNationality(ctx, name); res != nil {
+			body.Country = res.Countries
+		}
+		return body
+	}
+}
+
+func (s *Server) serveScript(w http.ResponseWriter, r *http.Request) {
+	api := chi.URLParam(r, "api")
+	if api != APIAge && api != APIGender && api != APINationality {
+		writeError(w, http.StatusNotFound, "Unknown API "+strconv.Quote(api))
+		return
+	}
+	var responses []Response
+	if err := json.NewDecoder(r.Body).Decode(&responses); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid script: "+err.Error())
+		return
+	}
+	s.Script(api, responses...)
+	log.Info().Str("api", api).Int("responses", len(responses)).Msg("Scripted responses queued")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) serveResetScripts(w http.ResponseWriter, r *http.Request) {
+	s.ResetScripts()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) serveStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Stats())
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// AdminAuth пропускает к административным маршрутам только запросы с
+// заголовком Authorization: Bearer <token>. Токен сравнивается за постоянное
+// время, чтобы его нельзя было подобрать по времени ответа.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				log.Warn().
+					Str("path", r.URL.Path).
+					Str("remote_addr", r.RemoteAddr).
+					Msg("Rejected admin request without valid token")
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
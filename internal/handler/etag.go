@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+var errBadETag = errors.New("malformed ETag")
+
+// personETag строит ETag из версии записи.
+func personETag(p *entity.Person) string {
+	return fmt.Sprintf(`"%d"`, p.Version)
+}
+
+func setETag(w http.ResponseWriter, p *entity.Person) {
+	w.Header().Set("ETag", personETag(p))
+}
+
+// parseIfMatch возвращает версию из заголовка If-Match. nil означает, что
+// заголовок не передан или равен "*", т.е. проверка версии не нужна.
+func parseIfMatch(r *http.Request) (*int, error) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return nil, nil
+	}
+	// If-Match использует строгое сравнение, слабые ETag не подходят.
+	if strings.HasPrefix(h, "W/") || len(h) < 2 || h[0] != '"' || h[len(h)-1] != '"' {
+		return nil, errBadETag
+	}
+	version, err := strconv.Atoi(h[1 : len(h)-1])
+	if err != nil {
+		return nil, errBadETag
+	}
+	return &version, nil
+}
+
+// etagMatches проверяет заголовок If-None-Match для условного GET.
+func etagMatches(r *http.Request, p *entity.Person) bool {
+	etag := personETag(p)
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	for header, want := range map[string]int{
+		`"5"`:    5,
+		` "12" `: 12,
+	} {
+		r := httptest.NewRequest(http.MethodPut, "/api/persons/1", nil)
+		r.Header.Set("If-Match", header)
+		got, err := parseIfMatch(r)
+		if err != nil || got == nil || *got != want {
+			t.Errorf("parseIfMatch(%s) = %v, %v; want %d", header, got, err, want)
+		}
+	}
+}
+
+func TestParseIfMatchWithoutVersion(t *testing.T) {
+	for _, header := range []string{"", "*"} {
+		r := httptest.NewRequest(http.MethodPut, "/api/persons/1", nil)
+		if header != "" {
+			r.Header.Set("If-Match", header)
+		}
+		if got, err := parseIfMatch(r); err != nil || got != nil {
+			t.Errorf("parseIfMatch(%q) = %v, %v; want no version check", header, got, err)
+		}
+	}
+}
+
+func TestParseIfMatchRejectsMalformed(t *testing.T) {
+	for _, header := range []string{`W/"5"`, `5`, `"5`, `"`, `"five"`, `"5", "6"`} {
+		r := httptest.NewRequest(http.MethodPut, "/api/persons/1", nil)
+		r.Header.Set("If-Match", header)
+		if _, err := parseIfMatch(r); !errors.Is(err, errBadETag) {
+			t.Errorf("parseIfMatch(%s) err = %v, want errBadETag", header, err)
+		}
+	}
+}
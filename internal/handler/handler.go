@@ -3,10 +3,19 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"mime"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/k1lls3x/person-service/internal/cache"
+	"github.com/k1lls3x/person-service/internal/client"
 	"github.com/k1lls3x/person-service/internal/entity"
 	"github.com/k1lls3x/person-service/internal/service"
 	"github.com/rs/zerolog/log"
@@ -14,22 +23,29 @@ import (
 
 type Handler struct {
 	personService *service.PersonService
+	enrichCache   *cache.Fetcher
+	apiClient     *client.APIClient
 }
 
-func NewHandler(personService *service.PersonService) *Handler {
-	return &Handler{personService: personService}
+func NewHandler(personService *service.PersonService, enrichCache *cache.Fetcher, apiClient *client.APIClient) *Handler {
+	return &Handler{personService: personService, enrichCache: enrichCache, apiClient: apiClient}
 }
 
 // UpdatePerson godoc
 // @Summary Обновить данные человека по id
+// @Description Запись заменяется целиком. Переданные age, gender и nationality сохраняются как ручные (attribute_sources) и не перезаписываются обогащением; непереданные заполняются обогащением заново.
 // @Tags persons
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
 // @Param person body entity.UpdatePersonInput true "Новые данные"
+// @Param If-Match header string false "ETag, полученный при чтении записи"
 // @Success 200 {object} entity.Person
 // @Failure 400 {string} string "bad request"
 // @Failure 404 {string} string "not found"
+// @Failure 412 {string} string "precondition failed"
+// @Failure 502 {string} string "внешний API обогащения вернул ошибку"
+// @Failure 503 {string} string "лимит внешнего API исчерпан (см. Retry-After) или открыт circuit breaker"
 // @Failure 500 {string} string "server error"
 // @Router /api/persons/{id} [put]
 func (h *Handler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
@@ -40,33 +56,126 @@ func (h *Handler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid id", http.StatusBadRequest)
 		return
 	}
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
 	var input entity.UpdatePersonInput
 
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
-	person, err := h.personService.UpdatePerson(id, &input)
+	person, err := h.personService.UpdatePerson(id, &input, expectedVersion)
 	if err != nil {
-		if errors.Is(err, service.ErrNotFound) {
+		switch {
+		case errors.Is(err, service.ErrInvalidInput):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrNotFound):
 			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
+		case errors.Is(err, service.ErrVersionConflict):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		case errors.Is(err, service.ErrEnrichmentFailed):
+			writeEnrichmentError(w, err)
+		default:
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
+	setETag(w, person)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(person)
 
 }
 
+// PatchPerson godoc
+// @Summary Частично обновить данные человека (JSON Merge Patch, RFC 7396)
+// @Description Изменяются только переданные поля, явный null очищает значение. Переданные age, gender и nationality становятся ручными и не перезаписываются обогащением; null снимает ручное значение.
+// @Description Обогащение перезапускается при смене имени или снятии ручного значения.
+// @Tags persons
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path int true "ID"
+// @Param person body entity.PatchPersonInput true "Изменяемые поля"
+// @Param If-Match header string false "ETag, полученный при чтении записи"
+// @Success 200 {object} entity.Person
+// @Failure 400 {string} string "bad request"
+// @Failure 404 {string} string "not found"
+// @Failure 412 {string} string "precondition failed"
+// @Failure 415 {string} string "unsupported media type"
+// @Failure 502 {string} string "внешний API обогащения вернул ошибку"
+// @Failure 503 {string} string "лимит внешнего API исчерпан (см. Retry-After) или открыт circuit breaker"
+// @Failure 500 {string} string "server error"
+// @Router /api/persons/{id} [patch]
+func (h *Handler) PatchPerson(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+			http.Error(w, "Content-Type must be application/merge-patch+json", http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
+	var input entity.PatchPersonInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	person, err := h.personService.PatchPerson(id, &input, expectedVersion)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidInput):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrVersionConflict):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		case errors.Is(err, service.ErrEnrichmentFailed):
+			writeEnrichmentError(w, err)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	setETag(w, person)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(person)
+}
+
 // CreatePerson godoc
 // @Summary Создать нового человека
 // @Tags persons
 // @Accept json
 // @Produce json
+// @Description Переданные age, gender и nationality сохраняются как ручные (attribute_sources) и не перезаписываются обогащением.
+// @Description Заголовок Idempotency-Key делает запрос безопасным для повтора: повтор с тем же ключом и телом возвращает исходный ответ.
+// @Description В асинхронном режиме (async=true или Prefer: respond-async) запись сохраняется сразу со статусом обогащения pending и возвращается 202.
 // @Param person body entity.CreatePersonInput true "Персона"
+// @Param Idempotency-Key header string false "Ключ идемпотентности"
+// @Param async query bool false "Обогатить запись в фоне"
+// @Param Prefer header string false "respond-async — обогатить запись в фоне"
 // @Success 201 {object} entity.Person
+// @Success 202 {object} entity.Person "обогащение поставлено в очередь"
+// @Failure 400 {string} string "bad request"
+// @Failure 422 {string} string "idempotency key reused with different body or enrichment mode"
+// @Failure 502 {string} string "внешний API обогащения вернул ошибку"
+// @Failure 503 {string} string "лимит внешнего API исчерпан (см. Retry-After) или открыт circuit breaker"
+// @Failure 500 {string} string "server error"
 // @Router /api/persons [post]
 func (h *Handler) CreatePerson(w http.ResponseWriter, r *http.Request) {
 	log.Debug().Msg("CreatePerson handler called")
@@ -80,28 +189,120 @@ func (h *Handler) CreatePerson(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Name and surname are required", http.StatusBadRequest)
 		return
 	}
-	person, err := h.personService.CreatePerson(&input)
+
+	async, err := h.wantsAsync(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var (
+		person   *entity.Person
+		status   int
+		replayed bool
+	)
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		person, status, replayed, err = h.personService.CreatePersonIdempotent(key, &input, async)
+	} else {
+		person, err = h.personService.CreatePerson(&input, async)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidInput):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case errors.Is(err, service.ErrEnrichmentFailed):
+			writeEnrichmentError(w, err)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
 
-	w.WriteHeader(http.StatusCreated)
+	setETag(w, person)
+	w.Header().Set("Location", "/api/persons/"+strconv.Itoa(person.ID))
+	// Повтор по ключу идемпотентности отвечает статусом исходного ответа.
+	if status == 0 {
+		status = http.StatusCreated
+		if person.EnrichmentStatus == entity.EnrichmentPending {
+			status = http.StatusAccepted
+		}
+	}
+	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(person); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
 }
 
+// CreatePersonsBatch godoc
+// @Summary Создать пакет людей
+// @Description ФИО обогащаются параллельно, одинаковые ФИО обогащаются один раз, а каждое имя запрашивается у провайдера, зависящего только от имени, один раз на пакет. В режиме atomic пакет сохраняется в одной транзакции, в режиме per_item — поэлементно.
+// @Tags persons
+// @Accept json
+// @Produce json
+// @Param persons body []entity.CreatePersonInput true "Персоны"
+// @Param mode query string false "Режим сохранения: atomic (по умолчанию) или per_item"
+// @Success 201 {array} entity.BatchItemResult
+// @Success 207 {array} entity.BatchItemResult "часть элементов не сохранена"
+// @Failure 400 {string} string "bad request"
+// @Failure 422 {array} entity.BatchItemResult "атомарный пакет не сохранён"
+// @Failure 500 {string} string "server error"
+// @Router /api/persons/batch [post]
+func (h *Handler) CreatePersonsBatch(w http.ResponseWriter, r *http.Request) {
+	mode := entity.BatchModeAtomic
+	if m := r.URL.Query().Get("mode"); m != "" {
+		mode = entity.BatchMode(m)
+	}
+
+	var inputs []entity.CreatePersonInput
+	if err := json.NewDecoder(r.Body).Decode(&inputs); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.personService.CreatePersonsBatch(inputs, mode)
+	status := http.StatusCreated
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, service.ErrBatchFailed):
+		status = http.StatusUnprocessableEntity
+	case err != nil && results == nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	case err != nil:
+		status = http.StatusInternalServerError
+	default:
+		for _, res := range results {
+			if res.Status != http.StatusCreated {
+				status = http.StatusMultiStatus
+				break
+			}
+		}
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(results)
+}
+
 // DeletePerson godoc
 // @Summary Удалить человека по id
+// @Description Удаление мягкое: запись скрывается из выдачи и может быть восстановлена до очистки.
 // @Tags persons
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-Match header string false "ETag, полученный при чтении записи"
 // @Success 204 {string} string "No Content"
 // @Failure 400 {string} string "bad request"
 // @Failure 404 {string} string "not found"
+// @Failure 412 {string} string "precondition failed"
 // @Failure 500 {string} string "server error"
 // @Router /api/persons/{id} [delete]
 func (h *Handler) DeletePerson(w http.ResponseWriter, r *http.Request) {
@@ -111,7 +312,247 @@ func (h *Handler) DeletePerson(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid id", http.StatusBadRequest)
 		return
 	}
-	if err := h.personService.DeletePersonById(id); err != nil {
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+	if err := h.personService.DeletePersonById(id, expectedVersion); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrVersionConflict):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestorePerson godoc
+// @Summary Восстановить мягко удалённого человека
+// @Tags persons
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} entity.Person
+// @Failure 400 {string} string "bad request"
+// @Failure 404 {string} string "not found"
+// @Failure 409 {string} string "person is not deleted"
+// @Failure 500 {string} string "server error"
+// @Router /api/persons/{id}/restore [post]
+func (h *Handler) RestorePerson(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+	person, err := h.personService.RestorePerson(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrNotDeleted):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	setETag(w, person)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(person)
+}
+
+// PurgeDeletedPersons godoc
+// @Summary Окончательно удалить давно удалённых людей
+// @Description Физически удаляет записи, мягко удалённые раньше срока хранения (PURGE_RETENTION или olderThan). История изменений удалённых записей сохраняется; при PURGE_ERASE_HISTORY=true она стирается и остаётся только отметка purge без данных.
+// @Tags admin
+// @Produce json
+// @Param olderThan query string false "Срок хранения, например 720h"
+// @Success 200 {object} entity.PurgeResult
+// @Security AdminToken
+// @Failure 400 {string} string "bad request"
+// @Failure 401 {string} string "unauthorized"
+// @Failure 500 {string} string "server error"
+// @Router /api/admin/persons/purge [post]
+func (h *Handler) PurgeDeletedPersons(w http.ResponseWriter, r *http.Request) {
+	var olderThan time.Duration
+	if s := r.URL.Query().Get("olderThan"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			http.Error(w, "olderThan must be a positive duration, e.g. 720h", http.StatusBadRequest)
+			return
+		}
+		olderThan = d
+	}
+	n, err := h.personService.PurgeDeletedPersons(olderThan)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entity.PurgeResult{Purged: n})
+}
+
+// EnrichPerson godoc
+// @Summary Заново обогатить человека
+// @Description Повторно запрашивает возраст, пол и национальность. Атрибуты, которые не удалось получить из-за ошибки API, сохраняют прежние значения.
+// @Description В асинхронном режиме (async=true или Prefer: respond-async) запись ставится в очередь фонового обогащения и возвращается 202.
+// @Tags persons
+// @Produce json
+// @Param id path int true "ID"
+// @Param async query bool false "Обогатить запись в фоне"
+// @Param Prefer header string false "respond-async — обогатить запись в фоне"
+// @Success 200 {object} entity.Person
+// @Success 202 {object} entity.Person "обогащение поставлено в очередь"
+// @Failure 400 {string} string "bad request"
+// @Failure 404 {string} string "not found"
+// @Failure 422 {string} string "внешний API отклонил имя"
+// @Failure 502 {string} string "внешний API обогащения вернул ошибку"
+// @Failure 503 {string} string "лимит внешнего API исчерпан (см. Retry-After) или открыт circuit breaker"
+// @Failure 504 {string} string "внешний API не ответил вовремя"
+// @Failure 500 {string} string "server error"
+// @Router /api/persons/{id}/enrich [post]
+func (h *Handler) EnrichPerson(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	async, err := h.wantsAsync(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	person, err := h.personService.ReenrichPerson(id, async)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrEnrichmentFailed):
+			writeEnrichmentError(w, err)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, person)
+	if async {
+		w.Header().Set("Location", "/api/persons/"+strconv.Itoa(person.ID))
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(person)
+}
+
+// EnrichPersons godoc
+// @Summary Заново обогатить людей по фильтру
+// @Description Ставит подходящие записи в очередь фонового обогащения. Нужен хотя бы один фильтр; записи, уже стоящие в очереди, пропускаются.
+// @Tags persons
+// @Produce json
+// @Param q query string false "Нечёткий поиск по ФИО с учётом опечаток и транслитерации"
+// @Param name query string false "Имя"
+// @Param surname query string false "Фамилия"
+// @Param patronymic query string false "Отчество"
+// @Param gender query string false "Пол"
+// @Param nationality query string false "Национальность"
+// @Param minAge query int false "Мин. возраст"
+// @Param maxAge query int false "Макс. возраст"
+// @Param enrichmentStatus query string false "Статус обогащения: pending, done, partial или failed"
+// @Param missing query bool false "Только записи с незаполненным возрастом, полом или национальностью"
+// @Param minGenderProbability query number false "Мин. вероятность пола (0..1)"
+// @Param minNationalityProbability query number false "Мин. вероятность основной национальности (0..1)"
+// @Param minAgeSampleCount query int false "Мин. число наблюдений, на которых основан возраст"
+// @Success 202 {object} entity.EnrichQueued
+// @Failure 400 {string} string "bad request"
+// @Failure 500 {string} string "server error"
+// @Router /api/persons/enrich [post]
+func (h *Handler) EnrichPersons(w http.ResponseWriter, r *http.Request) {
+	filter, err := parsePersonFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.personService.ReenrichPersons(filter)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidInput) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(entity.EnrichQueued{Queued: n})
+}
+
+// GetEnrichmentCacheStats godoc
+// @Summary Счётчики кэша обогащения
+// @Description Попадания по уровням (память, PostgreSQL), попадания в негативные записи и промахи с момента запуска.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} cache.Stats
+// @Router /api/admin/enrichment/cache [get]
+func (h *Handler) GetEnrichmentCacheStats(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.enrichCache.Stats())
+}
+
+// GetEnrichmentProviderStats godoc
+// @Summary Состояние внешних API
+// @Description Для каждого API: состояние circuit breaker (closed, open, half-open) и счётчики с момента запуска — запросы, повторные попытки, запросы, не удавшиеся после всех попыток, и запросы, отклонённые открытым breaker'ом.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]client.ProviderStats
+// @Router /api/admin/enrichment/providers [get]
+func (h *Handler) GetEnrichmentProviderStats(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.apiClient.Stats())
+}
+
+// GetPerson godoc
+// @Summary Получить человека по id
+// @Tags persons
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param asOf query string false "Восстановить состояние на момент времени (RFC 3339)"
+// @Param If-None-Match header string false "ETag закэшированной версии"
+// @Success 200 {object} entity.Person
+// @Success 304 {string} string "not modified"
+// @Failure 400 {string} string "bad request"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "server error"
+// @Router /api/persons/{id} [get]
+func (h *Handler) GetPerson(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var person *entity.Person
+	if asOfStr := r.URL.Query().Get("asOf"); asOfStr != "" {
+		asOf, perr := time.Parse(time.RFC3339, asOfStr)
+		if perr != nil {
+			http.Error(w, "asOf must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		person, err = h.personService.GetPersonAsOf(id, asOf)
+	} else {
+		person, err = h.personService.GetPersonById(id)
+	}
+	if err != nil {
 		if errors.Is(err, service.ErrNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 		} else {
@@ -119,7 +560,44 @@ func (h *Handler) DeletePerson(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
+	setETag(w, person)
+	if etagMatches(r, person) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(person)
+}
+
+// GetPersonHistory godoc
+// @Summary Получить историю изменений человека
+// @Description Каждая запись содержит операцию, источник изменения, время и значения до и после. Окончательное удаление (purge) добавляет отметку purge с последними данными записи; при PURGE_ERASE_HISTORY=true история стирается и остаётся только отметка purge без данных.
+// @Tags persons
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {array} entity.PersonHistoryEntry
+// @Failure 400 {string} string "bad request"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "server error"
+// @Router /api/persons/{id}/history [get]
+func (h *Handler) GetPersonHistory(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+	history, err := h.personService.GetPersonHistory(id)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
 }
 
 // GetPersons godoc
@@ -127,69 +605,262 @@ func (h *Handler) DeletePerson(w http.ResponseWriter, r *http.Request) {
 // @Tags persons
 // @Accept json
 // @Produce json
+// @Param q query string false "Нечёткий поиск по ФИО с учётом опечаток и транслитерации"
 // @Param name query string false "Имя"
 // @Param surname query string false "Фамилия"
+// @Param patronymic query string false "Отчество"
 // @Param gender query string false "Пол"
 // @Param nationality query string false "Национальность"
 // @Param minAge query int false "Мин. возраст"
 // @Param maxAge query int false "Макс. возраст"
+// @Param enrichmentStatus query string false "Статус обогащения: pending, done, partial или failed"
+// @Param missing query bool false "Только записи с незаполненным возрастом, полом или национальностью"
+// @Param minGenderProbability query number false "Мин. вероятность пола (0..1)"
+// @Param minNationalityProbability query number false "Мин. вероятность основной национальности (0..1)"
+// @Param minAgeSampleCount query int false "Мин. число наблюдений, на которых основан возраст"
 // @Param page query int false "Страница"
 // @Param pageSize query int false "Размер страницы"
-// @Success 200 {array} entity.Person
+// @Param cursor query string false "Курсор keyset-пагинации (пустое значение — первая страница)"
+// @Param count query string false "Подсчёт total: exact (по умолчанию), estimated или none"
+// @Param includeDeleted query bool false "Включить мягко удалённые записи"
+// @Param sort query string false "Сортировка через запятую, минус — по убыванию: surname,-age,created_at"
+// @Success 200 {object} entity.PersonList
+// @Failure 400 {string} string "bad request"
 // @Router /api/persons [get]
 func (h *Handler) GetPersons(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 
-	filter := entity.PersonFilter{
-		Name:        getStringPtr(q.Get("name")),
-		Surname:     getStringPtr(q.Get("surname")),
-		Gender:      getStringPtr(q.Get("gender")),
-		Nationality: getStringPtr(q.Get("nationality")),
+	filter, err := parsePersonFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	if minAgeStr := q.Get("minAge"); minAgeStr != "" {
-		if minAge, err := strconv.Atoi(minAgeStr); err == nil {
-			filter.MinAge = &minAge
+	if pageStr := q.Get("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil {
+			filter.Page = page
 		} else {
-			http.Error(w, "minAge must be an integer", http.StatusBadRequest)
+			http.Error(w, "page must be an integer", http.StatusBadRequest)
 			return
 		}
 	}
 
-	if maxAgeStr := q.Get("maxAge"); maxAgeStr != "" {
-		if maxAge, err := strconv.Atoi(maxAgeStr); err == nil {
-			filter.MaxAge = &maxAge
+	if pageSizeStr := q.Get("pageSize"); pageSizeStr != "" {
+		if pageSize, err := strconv.Atoi(pageSizeStr); err == nil {
+			filter.PageSize = pageSize
 		} else {
-			http.Error(w, "maxAge must be an integer", http.StatusBadRequest)
+			http.Error(w, "pageSize must be an integer", http.StatusBadRequest)
 			return
 		}
 	}
 
-	if pageStr := q.Get("page"); pageStr != "" {
-		if page, err := strconv.Atoi(pageStr); err == nil {
-			filter.Page = page
+	switch count := entity.CountMode(q.Get("count")); count {
+	case "", entity.CountExact, entity.CountEstimated, entity.CountNone:
+		filter.Count = count
+	default:
+		http.Error(w, "count must be one of exact, estimated, none", http.StatusBadRequest)
+		return
+	}
+
+	if includeDeletedStr := q.Get("includeDeleted"); includeDeletedStr != "" {
+		if includeDeleted, err := strconv.ParseBool(includeDeletedStr); err == nil {
+			filter.IncludeDeleted = includeDeleted
 		} else {
-			http.Error(w, "page must be an integer", http.StatusBadRequest)
+			http.Error(w, "includeDeleted must be a boolean", http.StatusBadRequest)
 			return
 		}
 	}
 
-	if pageSizeStr := q.Get("pageSize"); pageSizeStr != "" {
-		if pageSize, err := strconv.Atoi(pageSizeStr); err == nil {
-			filter.PageSize = pageSize
-		} else {
-			http.Error(w, "pageSize must be an integer", http.StatusBadRequest)
+	if sortStr := q.Get("sort"); sortStr != "" {
+		sort, err := parseSort(sortStr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.Sort = sort
+	}
+
+	if q.Has("cursor") {
+		if filter.Page != 0 {
+			http.Error(w, "cursor and page cannot be used together", http.StatusBadRequest)
 			return
 		}
+		cursor := q.Get("cursor")
+		filter.Cursor = &cursor
 	}
 
-	persons, err := h.personService.GetPersons(filter)
+	list, err := h.personService.GetPersons(filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrInvalidInput) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
+	list.Links = pageLinks(r, list)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(persons)
+	json.NewEncoder(w).Encode(list)
+}
+
+// parsePersonFilter разбирает условия отбора записей, общие для списка и
+// массового повторного обогащения.
+func parsePersonFilter(q url.Values) (entity.PersonFilter, error) {
+	filter := entity.PersonFilter{
+		Query:       getStringPtr(strings.TrimSpace(q.Get("q"))),
+		Name:        getStringPtr(q.Get("name")),
+		Surname:     getStringPtr(q.Get("surname")),
+		Patronymic:  getStringPtr(q.Get("patronymic")),
+		Gender:      getStringPtr(q.Get("gender")),
+		Nationality: getStringPtr(q.Get("nationality")),
+	}
+
+	if minAgeStr := q.Get("minAge"); minAgeStr != "" {
+		minAge, err := strconv.Atoi(minAgeStr)
+		if err != nil {
+			return filter, errors.New("minAge must be an integer")
+		}
+		filter.MinAge = &minAge
+	}
+
+	if maxAgeStr := q.Get("maxAge"); maxAgeStr != "" {
+		maxAge, err := strconv.Atoi(maxAgeStr)
+		if err != nil {
+			return filter, errors.New("maxAge must be an integer")
+		}
+		filter.MaxAge = &maxAge
+	}
+
+	switch status := entity.EnrichmentStatus(q.Get("enrichmentStatus")); status {
+	case "":
+	case entity.EnrichmentPending, entity.EnrichmentDone, entity.EnrichmentPartial, entity.EnrichmentFailed:
+		filter.EnrichmentStatus = &status
+	default:
+		return filter, errors.New("enrichmentStatus must be one of pending, done, partial, failed")
+	}
+
+	if s := q.Get("minGenderProbability"); s != "" {
+		p, err := parseProbability(s)
+		if err != nil {
+			return filter, fmt.Errorf("minGenderProbability %w", err)
+		}
+		filter.MinGenderProbability = &p
+	}
+
+	if s := q.Get("minNationalityProbability"); s != "" {
+		p, err := parseProbability(s)
+		if err != nil {
+			return filter, fmt.Errorf("minNationalityProbability %w", err)
+		}
+		filter.MinNationalityProbability = &p
+	}
+
+	if s := q.Get("minAgeSampleCount"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return filter, errors.New("minAgeSampleCount must be a non-negative integer")
+		}
+		filter.MinAgeSampleCount = &n
+	}
+
+	if missingStr := q.Get("missing"); missingStr != "" {
+		missing, err := strconv.ParseBool(missingStr)
+		if err != nil {
+			return filter, errors.New("missing must be a boolean")
+		}
+		filter.MissingAttributes = missing
+	}
+
+	return filter, nil
+}
+
+func parseProbability(s string) (float64, error) {
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil || p < 0 || p > 1 {
+		return 0, errors.New("must be a number between 0 and 1")
+	}
+	return p, nil
+}
+
+// pageLinks строит ссылки на соседние страницы, сохраняя остальные
+// параметры исходного запроса.
+func pageLinks(r *http.Request, list *entity.PersonList) entity.PageLinks {
+	link := func(set func(q url.Values)) *string {
+		q := r.URL.Query()
+		set(q)
+		u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+		s := u.String()
+		return &s
+	}
+
+	var links entity.PageLinks
+	if list.NextCursor != nil {
+		links.Next = link(func(q url.Values) { q.Set("cursor", *list.NextCursor) })
+		return links
+	}
+	if list.Page == 0 {
+		// Keyset-пагинация идёт только вперёд.
+		return links
+	}
+	if list.HasMore {
+		links.Next = link(func(q url.Values) { q.Set("page", strconv.Itoa(list.Page+1)) })
+	}
+	if list.Page > 1 {
+		links.Prev = link(func(q url.Values) { q.Set("page", strconv.Itoa(list.Page-1)) })
+	}
+	return links
+}
+
+// wantsAsync определяет режим создания: параметр async, затем заголовок
+// Prefer: respond-async, иначе — режим по умолчанию из конфигурации.
+func (h *Handler) wantsAsync(r *http.Request) (bool, error) {
+	if asyncStr := r.URL.Query().Get("async"); asyncStr != "" {
+		async, err := strconv.ParseBool(asyncStr)
+		if err != nil {
+			return false, errors.New("async must be a boolean")
+		}
+		return async, nil
+	}
+	for _, pref := range strings.Split(r.Header.Get("Prefer"), ",") {
+		if strings.EqualFold(strings.TrimSpace(pref), "respond-async") {
+			return true, nil
+		}
+	}
+	return h.personService.AsyncEnrichmentDefault(), nil
+}
+
+// parseSort разбирает параметр sort вида "surname,-age,created_at".
+func parseSort(s string) ([]entity.SortField, error) {
+	parts := strings.Split(s, ",")
+	fields := make([]entity.SortField, 0, len(parts))
+	seen := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		field := entity.SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if _, ok := entity.PersonSortColumns[field.Field]; !ok {
+			allowed := make([]string, 0, len(entity.PersonSortColumns))
+			for name := range entity.PersonSortColumns {
+				allowed = append(allowed, name)
+			}
+			slices.Sort(allowed)
+			return nil, fmt.Errorf("invalid sort field %q, allowed: %s", part, strings.Join(allowed, ", "))
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("duplicate sort field %q", field.Field)
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// writeEnrichmentError отвечает на ошибку обогащения статусом, подобранным
+// сервисом, и передаёт клиенту время сброса лимита внешнего API.
+func writeEnrichmentError(w http.ResponseWriter, err error) {
+	if d := service.RetryAfter(err); d > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+	}
+	http.Error(w, err.Error(), service.EnrichmentErrorStatus(err))
 }
 
 func getStringPtr(s string) *string {
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+func TestParseSort(t *testing.T) {
+	got, err := parseSort(" surname , -age,created_at")
+	if err != nil {
+		t.Fatalf("parseSort: %v", err)
+	}
+	want := []entity.SortField{
+		{Field: "surname"},
+		{Field: "age", Desc: true},
+		{Field: "created_at"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseSort = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseSortRejectsInvalidFields(t *testing.T) {
+	for name, input := range map[string]string{
+		"unknown field": "password",
+		"empty field":   "surname,,age",
+		"only minus":    "-",
+		"double minus":  "--age",
+		"duplicate":     "age,-age",
+		"uppercase":     "Surname",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseSort(input); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
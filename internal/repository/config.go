@@ -3,32 +3,123 @@ package repository
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
 )
 
 type Config struct {
-	Host              string
-	Port              string
-	User              string
-	Password          string
-	Name              string
-	AgeAPIURL         string
-	GenderAPIURL      string
-	NationalityAPIURL string
-	LogLevel          string
+	Host                        string
+	Port                        string
+	User                        string
+	Password                    string
+	Name                        string
+	AgeAPIURL                   string
+	GenderAPIURL                string
+	NationalityAPIURL           string
+	LogLevel                    string
+	IdempotencyKeyTTL           time.Duration
+	IdempotencySweepInterval    time.Duration
+	BatchEnrichConcurrency      int
+	BatchMaxSize                int
+	SearchSimilarityThreshold   float64
+	PurgeRetention              time.Duration
+	PurgeEraseHistory           bool
+	EnrichAsync                 bool
+	EnrichWorkers               int
+	EnrichMaxAttempts           int
+	EnrichPollInterval          time.Duration
+	EnrichLockTimeout           time.Duration
+	EnrichPolicy                string
+	EnrichSweepInterval         time.Duration
+	EnrichSweepBatch            int
+	EnrichSweepConcurrency      int
+	EnrichSweepRate             float64
+	EnrichStaleAfter            time.Duration
+	EnrichRetryAfter            time.Duration
+	EnrichCacheSize             int
+	EnrichCacheTTL              time.Duration
+	EnrichCacheNegativeTTL      time.Duration
+	EnrichCachePostgres         bool
+	EnrichCacheSweepInterval    time.Duration
+	EnrichCoalesce              bool
+	EnrichCoalesceWindow        time.Duration
+	APIRetryBaseDelay           time.Duration
+	APIRetryMaxDelay            time.Duration
+	AgeAPIRetryAttempts         int
+	GenderAPIRetryAttempts      int
+	NationalityAPIRetryAttempts int
+	BreakerFailureThreshold     int
+	BreakerErrorRate            float64
+	BreakerWindow               int
+	BreakerMinRequests          int
+	BreakerOpenTimeout          time.Duration
+	AgeProviders                []string
+	GenderProviders             []string
+	NationalityProviders        []string
+	EnrichDatasetPath           string
+	AdminToken                  string
 }
 
 func LoadConfigFromEnv() *Config {
-	return &Config{
-		Host:              os.Getenv("DB_HOST"),
-		Port:              os.Getenv("DB_PORT"),
-		User:              os.Getenv("DB_USER"),
-		Password:          os.Getenv("DB_PASSWORD"),
-		Name:              os.Getenv("DB_NAME"),
-		AgeAPIURL:         os.Getenv("AGE_API_URL"),
-		GenderAPIURL:      os.Getenv("GENDER_API_URL"),
-		NationalityAPIURL: os.Getenv("NATIONALITY_API_URL"),
-		LogLevel:          os.Getenv("LOG_LEVEL"),
+	cfg := &Config{
+		Host:                      os.Getenv("DB_HOST"),
+		Port:                      os.Getenv("DB_PORT"),
+		User:                      os.Getenv("DB_USER"),
+		Password:                  os.Getenv("DB_PASSWORD"),
+		Name:                      os.Getenv("DB_NAME"),
+		AgeAPIURL:                 os.Getenv("AGE_API_URL"),
+		GenderAPIURL:              os.Getenv("GENDER_API_URL"),
+		NationalityAPIURL:         os.Getenv("NATIONALITY_API_URL"),
+		LogLevel:                  os.Getenv("LOG_LEVEL"),
+		IdempotencyKeyTTL:         getDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		IdempotencySweepInterval:  getDuration("IDEMPOTENCY_SWEEP_INTERVAL", time.Hour),
+		BatchEnrichConcurrency:    getInt("BATCH_ENRICH_CONCURRENCY", 8),
+		BatchMaxSize:              getInt("BATCH_MAX_SIZE", 500),
+		SearchSimilarityThreshold: getFloat("SEARCH_SIMILARITY_THRESHOLD", 0.3),
+		PurgeRetention:            getDuration("PURGE_RETENTION", 30*24*time.Hour),
+		PurgeEraseHistory:         getBool("PURGE_ERASE_HISTORY", false),
+		EnrichAsync:               getBool("ENRICH_ASYNC", false),
+		EnrichWorkers:             getInt("ENRICH_WORKERS", 4),
+		EnrichMaxAttempts:         getInt("ENRICH_MAX_ATTEMPTS", 5),
+		EnrichPollInterval:        getDuration("ENRICH_POLL_INTERVAL", time.Second),
+		EnrichLockTimeout:         getDuration("ENRICH_LOCK_TIMEOUT", time.Minute),
+		EnrichPolicy:              getString("ENRICH_POLICY", "partial"),
+		EnrichSweepInterval:       getDuration("ENRICH_SWEEP_INTERVAL", 10*time.Minute),
+		EnrichSweepBatch:          getInt("ENRICH_SWEEP_BATCH", 100),
+		EnrichSweepConcurrency:    getInt("ENRICH_SWEEP_CONCURRENCY", 2),
+		EnrichSweepRate:           getFloat("ENRICH_SWEEP_RATE", 1),
+		EnrichStaleAfter:          getDuration("ENRICH_STALE_AFTER", 30*24*time.Hour),
+		EnrichRetryAfter:          getDuration("ENRICH_RETRY_AFTER", 24*time.Hour),
+		EnrichCacheSize:           getInt("ENRICH_CACHE_SIZE", 10000),
+		EnrichCacheTTL:            getDuration("ENRICH_CACHE_TTL", 30*24*time.Hour),
+		EnrichCacheNegativeTTL:    getDuration("ENRICH_CACHE_NEGATIVE_TTL", 24*time.Hour),
+		EnrichCachePostgres:       getBool("ENRICH_CACHE_POSTGRES", true),
+		EnrichCacheSweepInterval:  getDuration("ENRICH_CACHE_SWEEP_INTERVAL", time.Hour),
+		EnrichCoalesce:            getBool("ENRICH_COALESCE", true),
+		EnrichCoalesceWindow:      getDuration("ENRICH_COALESCE_WINDOW", 10*time.Millisecond),
+		APIRetryBaseDelay:         getDuration("API_RETRY_BASE_DELAY", 100*time.Millisecond),
+		APIRetryMaxDelay:          getDuration("API_RETRY_MAX_DELAY", 2*time.Second),
+		BreakerFailureThreshold:   getInt("BREAKER_FAILURE_THRESHOLD", 5),
+		BreakerErrorRate:          getFloat("BREAKER_ERROR_RATE", 0.5),
+		BreakerWindow:             getInt("BREAKER_WINDOW", 20),
+		BreakerMinRequests:        getInt("BREAKER_MIN_REQUESTS", 10),
+		BreakerOpenTimeout:        getDuration("BREAKER_OPEN_TIMEOUT", 30*time.Second),
+		AgeProviders:              getList("ENRICH_AGE_PROVIDERS", "api"),
+		GenderProviders:           getList("ENRICH_GENDER_PROVIDERS", "patronymic,api"),
+		NationalityProviders:      getList("ENRICH_NATIONALITY_PROVIDERS", "api"),
+		EnrichDatasetPath:         os.Getenv("ENRICH_DATASET_PATH"),
+		AdminToken:                os.Getenv("ADMIN_TOKEN"),
 	}
+	// Число попыток по умолчанию общее, но его можно переопределить для
+	// отдельного API.
+	attempts := getInt("API_RETRY_ATTEMPTS", 3)
+	cfg.AgeAPIRetryAttempts = getInt("AGE_API_RETRY_ATTEMPTS", attempts)
+	cfg.GenderAPIRetryAttempts = getInt("GENDER_API_RETRY_ATTEMPTS", attempts)
+	cfg.NationalityAPIRetryAttempts = getInt("NATIONALITY_API_RETRY_ATTEMPTS", attempts)
+	return cfg
 }
 func (cfg *Config) DSN() string {
 	return fmt.Sprintf(
@@ -36,3 +127,87 @@ func (cfg *Config) DSN() string {
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name,
 	)
 }
+
+// getDuration читает длительность в формате time.ParseDuration ("90s", "24h").
+// При пустом или некорректном значении возвращается def.
+func getDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Warn().Str("key", key).Str("value", v).Msg("Invalid duration, using default")
+		return def
+	}
+	return d
+}
+
+// getInt читает положительное целое. При пустом или некорректном значении
+// возвращается def.
+func getInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Warn().Str("key", key).Str("value", v).Msg("Invalid integer, using default")
+		return def
+	}
+	return n
+}
+
+// getFloat читает положительное число с плавающей точкой. При пустом или
+// некорректном значении возвращается def.
+func getFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 {
+		log.Warn().Str("key", key).Str("value", v).Msg("Invalid number, using default")
+		return def
+	}
+	return f
+}
+
+// getBool читает логическое значение ("true", "1", "false", ...). При пустом
+// или некорректном значении возвращается def.
+func getBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Warn().Str("key", key).Str("value", v).Msg("Invalid boolean, using default")
+		return def
+	}
+	return b
+}
+
+// getList читает список значений через запятую. При пустом значении
+// используется def; значение "none" означает пустой список.
+func getList(key, def string) []string {
+	v := getString(key, def)
+	if strings.TrimSpace(v) == "none" {
+		return nil
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getString читает строковое значение. При пустом значении возвращается def.
+func getString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+// ErrBatchFailed возвращается в атомарном режиме, если хотя бы один элемент
+// пакета не прошёл валидацию или обогащение и транзакция не была выполнена.
+var ErrBatchFailed = errors.New("batch was not saved")
+
+// CreatePersonsBatch создаёт пакет записей. ФИО обогащаются параллельно
+// (не более cfg.BatchEnrichConcurrency одновременно), причём одинаковые ФИО
+// обогащаются только один раз, а провайдеры, которым нужно только имя
+// (внешние API, справочник), опрашиваются один раз на каждое имя. Результат
+// содержит статус для каждого элемента в порядке входного массива.
+func (s *PersonService) CreatePersonsBatch(inputs []entity.CreatePersonInput, mode entity.BatchMode) ([]entity.BatchItemResult, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: batch is empty", ErrInvalidInput)
+	}
+	if len(inputs) > s.cfg.BatchMaxSize {
+		return nil, fmt.Errorf("%w: batch size %d exceeds limit %d", ErrInvalidInput, len(inputs), s.cfg.BatchMaxSize)
+	}
+	if mode != entity.BatchModeAtomic && mode != entity.BatchModePerItem {
+		return nil, fmt.Errorf("%w: unknown batch mode %q", ErrInvalidInput, mode)
+	}
+
+	log.Info().Int("size", len(inputs)).Str("mode", string(mode)).Msg("Batch create started")
+
+	results := make([]entity.BatchItemResult, len(inputs))
+	names := make([]personName, 0, len(inputs))
+	seen := make(map[personName]struct{}, len(inputs))
+	for i, input := range inputs {
+		results[i].Index = i
+		if input.Name == "" || input.Surname == "" {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = "Name and surname are required"
+			continue
+		}
+		if err := validateAttributes(input.Age, input.Gender, input.Nationality); err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = err.Error()
+			continue
+		}
+		name := newPersonName(&input)
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	enriched := s.enrichNames(ctx, names)
+
+	persons := make([]*entity.Person, len(inputs))
+	failed := false
+	for i, input := range inputs {
+		if results[i].Status != 0 {
+			failed = true
+			continue
+		}
+		res := enriched[newPersonName(&input)]
+		if res.person == nil {
+			res.person = &entity.Person{}
+		}
+		person := &entity.Person{
+			Name:       input.Name,
+			Surname:    input.Surname,
+			Patronymic: input.Patronymic,
+		}
+		for _, attr := range Attributes {
+			attr.copyValue(person, res.person)
+		}
+		// ФИО обогащается один раз на весь пакет, поэтому ручные значения
+		// элемента накладываются поверх результата.
+		setManualAttributes(person, input.Age, input.Gender, input.Nationality)
+		state, err := withoutManual(person, res.state, res.err)
+		if err := s.applyEnrichment(person, state, err); err != nil {
+			failed = true
+			results[i].Status = EnrichmentErrorStatus(err)
+			results[i].Error = fmt.Sprintf("failed to enrich person: %v", err)
+			continue
+		}
+		persons[i] = person
+	}
+
+	if mode == entity.BatchModeAtomic {
+		if failed {
+			markAborted(results)
+			return results, ErrBatchFailed
+		}
+		if err := s.insertBatchAtomic(ctx, persons); err != nil {
+			for i := range results {
+				results[i].Status = http.StatusInternalServerError
+				results[i].Error = err.Error()
+				results[i].Person = nil
+			}
+			return results, err
+		}
+	} else {
+		for i, person := range persons {
+			if person == nil {
+				continue
+			}
+			err := s.inTx(ctx, entity.ChangeSourceImport, func(tx *sqlx.Tx) error {
+				return insertPerson(ctx, tx, person)
+			})
+			if err != nil {
+				log.Error().Err(err).Int("index", i).Msg("Failed to insert batch item")
+				results[i].Status = http.StatusInternalServerError
+				results[i].Error = err.Error()
+				persons[i] = nil
+			}
+		}
+	}
+
+	created := 0
+	for i, person := range persons {
+		if person != nil {
+			results[i].Status = http.StatusCreated
+			results[i].Person = person
+			created++
+		}
+	}
+
+	log.Info().
+		Int("size", len(inputs)).
+		Int("created", created).
+		Int("distinct_names", len(names)).
+		Msg("Batch create finished")
+	return results, nil
+}
+
+func (s *PersonService) insertBatchAtomic(ctx context.Context, persons []*entity.Person) error {
+	return s.inTx(ctx, entity.ChangeSourceImport, func(tx *sqlx.Tx) error {
+		for _, person := range persons {
+			if err := insertPerson(ctx, tx, person); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// markAborted помечает успешно подготовленные элементы атомарного пакета,
+// которые не были сохранены из-за ошибок в других элементах.
+func markAborted(results []entity.BatchItemResult) {
+	for i := range results {
+		if results[i].Status == 0 {
+			results[i].Status = http.StatusFailedDependency
+			results[i].Error = "not saved: another item in the atomic batch failed"
+		}
+	}
+}
+
+// personName — ФИО элемента пакета: некоторые провайдеры (patronymic)
+// учитывают не только имя, но и фамилию с отчеством.
+type personName struct {
+	name, surname, patronymic string
+}
+
+func newPersonName(input *entity.CreatePersonInput) personName {
+	n := personName{name: input.Name, surname: input.Surname}
+	if input.Patronymic != nil {
+		n.patronymic = *input.Patronymic
+	}
+	return n
+}
+
+func (n personName) person() *entity.Person {
+	person := &entity.Person{Name: n.name, Surname: n.surname}
+	if n.patronymic != "" {
+		person.Patronymic = &n.patronymic
+	}
+	return person
+}
+
+type nameEnrichment struct {
+	person *entity.Person
+	state  entity.EnrichmentState
+	err    error
+}
+
+// enrichNames обогащает каждое ФИО ровно один раз с ограниченным
+// параллелизмом. Провайдеры, зависящие только от имени, получают каждое имя
+// один раз на весь пакет: «Иван Иванов» и «Иван Петров» дают один запрос к
+// внешним API.
+func (s *PersonService) enrichNames(ctx context.Context, names []personName) map[personName]nameEnrichment {
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, s.cfg.BatchEnrichConcurrency)
+		results   = make(map[personName]nameEnrichment, len(names))
+		providers = s.providers.memoizeByName()
+	)
+
+	for _, name := range names {
+		wg.Add(1)
+		go func(name personName) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				mu.Lock()
+				results[name] = nameEnrichment{
+					state: providers.failedState(),
+					err:   ctx.Err(),
+				}
+				mu.Unlock()
+				return
+			}
+
+			person := name.person()
+			state, err := enrichFromProviders(ctx, providers, person)
+
+			mu.Lock()
+			results[name] = nameEnrichment{person: person, state: state, err: err}
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+	return results
+}
+
+// memoizeByName возвращает копию реестра, в цепочках которой провайдеры,
+// зависящие только от имени, запоминают ответы: каждое имя запрашивается у
+// провайдера один раз, сколько бы ФИО с этим именем ни обогащалось. Копия
+// предназначена для одного пакета и не ограничена по размеру.
+func (r *Registry) memoizeByName() *Registry {
+	memo := &nameMemo{calls: make(map[nameMemoKey]*nameMemoCall)}
+	memoized := NewRegistry()
+	for name, p := range r.providers {
+		memoized.providers[name] = p
+	}
+	for attr, chain := range r.chains {
+		wrapped := make([]Provider, len(chain))
+		for i, p := range chain {
+			if _, ok := p.(fullNameProvider); ok {
+				wrapped[i] = p
+			} else {
+				wrapped[i] = &memoProvider{Provider: p, memo: memo}
+			}
+		}
+		memoized.chains[attr] = wrapped
+	}
+	return memoized
+}
+
+type nameMemoKey struct {
+	provider string
+	attr     Attribute
+	name     string
+}
+
+// nameMemoCall — ответ провайдера на одно имя. Первый запросивший выполняет
+// запрос, остальные ждут закрытия done. Если ответ не запомнен (retry),
+// ожидавшие выполняют запрос заново.
+type nameMemoCall struct {
+	done  chan struct{}
+	value *entity.Person
+	found bool
+	err   error
+	retry bool
+}
+
+type nameMemo struct {
+	mu    sync.Mutex
+	calls map[nameMemoKey]*nameMemoCall
+}
+
+// call возвращает запомненный или выполняющийся вызов по ключу. Если вызова
+// ещё нет, он создаётся и leader = true: запрос выполняет вызывающий.
+func (m *nameMemo) call(key nameMemoKey) (call *nameMemoCall, leader bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if call, ok := m.calls[key]; ok {
+		return call, false
+	}
+	call = &nameMemoCall{done: make(chan struct{})}
+	m.calls[key] = call
+	return call, true
+}
+
+// forget удаляет вызов, чтобы следующий запросивший выполнил его заново.
+func (m *nameMemo) forget(key nameMemoKey) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.calls, key)
+}
+
+// memoProvider запоминает ответы провайдера по имени в nameMemo. Ошибки
+// контекста не запоминаются: дедлайн или отмена одного ФИО не должны
+// доставаться остальным записям с тем же именем, они повторяют запрос сами.
+type memoProvider struct {
+	Provider
+	memo *nameMemo
+}
+
+func (p *memoProvider) Enrich(ctx context.Context, attr Attribute, person *entity.Person) (bool, error) {
+	key := nameMemoKey{provider: p.Name(), attr: attr, name: person.Name}
+	for {
+		call, leader := p.memo.call(key)
+		if leader {
+			call.value = &entity.Person{Name: person.Name}
+			call.found, call.err = p.Provider.Enrich(ctx, attr, call.value)
+			if call.err != nil && (ctx.Err() != nil || isContextError(call.err)) {
+				call.retry = true
+				p.memo.forget(key)
+			}
+			close(call.done)
+		} else {
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return false, ctx.Err()
+			}
+			if call.retry {
+				continue
+			}
+		}
+		if call.found {
+			attr.copyValue(person, call.value)
+		}
+		return call.found, call.err
+	}
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+// slowFirstProvider не отвечает на первый запрос до дедлайна, а на
+// последующие сразу возвращает возраст.
+type slowFirstProvider struct {
+	calls atomic.Int32
+}
+
+func (*slowFirstProvider) Name() string            { return ProviderAPI }
+func (*slowFirstProvider) Supports(Attribute) bool { return true }
+
+func (p *slowFirstProvider) Enrich(ctx context.Context, _ Attribute, person *entity.Person) (bool, error) {
+	if p.calls.Add(1) == 1 {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+	age := 36
+	person.Age = &age
+	return true, nil
+}
+
+func TestMemoDoesNotShareContextErrors(t *testing.T) {
+	api := &slowFirstProvider{}
+	r := NewRegistry()
+	r.Register(api)
+	if err := r.Use(AttributeAge, ProviderAPI); err != nil {
+		t.Fatal(err)
+	}
+	chain := r.memoizeByName().chains[AttributeAge]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := chain[0].Enrich(ctx, AttributeAge, &entity.Person{Name: "Ivan"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the caller's deadline", err)
+	}
+
+	for range 2 {
+		person := &entity.Person{Name: "Ivan"}
+		found, err := chain[0].Enrich(context.Background(), AttributeAge, person)
+		if err != nil || !found || person.Age == nil || *person.Age != 36 {
+			t.Fatalf("found = %v, age = %v, err = %v; want age 36", found, person.Age, err)
+		}
+	}
+	if n := api.calls.Load(); n != 2 {
+		t.Errorf("provider got %d calls, want 2: one timed out, one memoized", n)
+	}
+}
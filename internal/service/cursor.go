@@ -0,0 +1,39 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidCursor возвращается, если курсор пагинации не удалось разобрать.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// personCursor — позиция последней выданной записи в порядке
+// (created_at DESC, id DESC). Клиенту отдаётся в непрозрачном виде.
+type personCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int       `json:"id"`
+}
+
+func encodeCursor(c personCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (personCursor, error) {
+	var c personCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if c.CreatedAt.IsZero() || c.ID <= 0 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
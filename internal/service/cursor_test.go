@@ -0,0 +1,39 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := personCursor{CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC), ID: 42}
+	got, err := decodeCursor(encodeCursor(want))
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("decodeCursor = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeCursorRejectsInvalidInput(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	for name, input := range map[string]string{
+		"empty":          "",
+		"not base64":     "!!!",
+		"padded base64":  base64.URLEncoding.EncodeToString([]byte(`{"t":"2024-05-01T12:30:00Z","id":1}`)),
+		"not json":       encode("cursor"),
+		"no time":        encode(`{"id":1}`),
+		"no id":          encode(`{"t":"2024-05-01T12:30:00Z"}`),
+		"negative id":    encode(`{"t":"2024-05-01T12:30:00Z","id":-1}`),
+		"malformed time": encode(`{"t":"yesterday","id":1}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := decodeCursor(input); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
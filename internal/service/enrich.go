@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -10,81 +13,310 @@ import (
 	"github.com/k1lls3x/person-service/internal/entity"
 )
 
-func enrichFromAPI(parentCtx context.Context, apiClient *client.APIClient, person *entity.Person) error {
+// ErrEnrichmentFailed возвращается, если обогащение не дало результата,
+// который можно сохранить при текущей политике. Оборачивает ошибки внешних
+// API, см. EnrichmentErrorStatus.
+var ErrEnrichmentFailed = errors.New("enrichment failed")
+
+// enrichFromProviders определяет включённые атрибуты параллельно, каждый —
+// своей цепочкой провайдеров. Полученные значения записываются в person, а
+// состояние каждого атрибута — в возвращаемый EnrichmentState; отключённые
+// и заданные вручную атрибуты не затрагиваются. Ошибка объединяет ошибки
+// всех несостоявшихся атрибутов; значения, полученные до неё, не теряются.
+func enrichFromProviders(parentCtx context.Context, providers *Registry, person *entity.Person) (entity.EnrichmentState, error) {
+	return enrichAttributes(parentCtx, providers, person, providers.Enabled())
+}
+
+// enrichAttributes — enrichFromProviders для части атрибутов: остальные
+// атрибуты person и их состояние не затрагиваются.
+func enrichAttributes(parentCtx context.Context, providers *Registry, person *entity.Person, attrs []Attribute) (entity.EnrichmentState, error) {
 	ctx, cancel := context.WithTimeout(parentCtx, 3*time.Second)
 	defer cancel()
 
 	type result struct {
-		age         *int
-		gender      *string
-		nationality *string
-		err         error
+		attr     Attribute
+		provider string
+		value    *entity.Person
+		err      error
 	}
 
 	log.Debug().
 		Str("name", person.Name).
-		Msg("Starting enrichment from APIs")
+		Msg("Starting enrichment from providers")
 
-	ch := make(chan result, 3)
-
-	go func() {
-		age, err := apiClient.FetchAge(ctx, person.Name)
-		if err != nil {
-			log.Error().Err(err).Str("name", person.Name).Msg("Failed to fetch age")
+	var enabled []Attribute
+	for _, attr := range attrs {
+		if len(providers.Chain(attr)) > 0 && !attr.manual(person) {
+			enabled = append(enabled, attr)
 		}
-		ch <- result{age: age, err: err}
-	}()
+	}
 
-	go func() {
-		nat, err := apiClient.FetchNationality(ctx, person.Name)
-		if err != nil {
-			log.Error().Err(err).Str("name", person.Name).Msg("Failed to fetch nationality")
-		}
-		ch <- result{nationality: nat, err: err}
-	}()
+	// Атрибут, не успевший ответить до таймаута, считается ошибочным.
+	var state entity.EnrichmentState
+	pending := make(map[Attribute]bool, len(enabled))
+	ch := make(chan result, len(enabled))
 
-	go func() {
-		gender, err := apiClient.FetchGender(ctx, person.Name)
-		if err != nil {
-			log.Error().Err(err).Str("name", person.Name).Msg("Failed to fetch gender")
-		}
-		ch <- result{gender: gender, err: err}
-	}()
+	for _, attr := range enabled {
+		*attr.state(&state) = entity.AttributeError
+		attr.copyValue(person, &entity.Person{})
+		pending[attr] = true
+		go func(attr Attribute) {
+			provider, value, err := resolveAttribute(ctx, attr, providers.Chain(attr), person)
+			if err != nil {
+				log.Error().Err(err).Str("attribute", string(attr)).Str("name", person.Name).Msg("Failed to enrich attribute")
+			}
+			ch <- result{attr: attr, provider: provider, value: value, err: err}
+		}(attr)
+	}
 
-	var finalError error
+	var errs []error
 
-	for i := 0; i < 3; i++ {
+collect:
+	for range enabled {
 		select {
 		case <-ctx.Done():
 			log.Error().
 				Str("name", person.Name).
 				Msg("Enrichment context deadline exceeded")
-			return ctx.Err()
+			for attr := range pending {
+				errs = append(errs, fmt.Errorf("%s: %w", attr, ctx.Err()))
+			}
+			break collect
 
 		case res := <-ch:
+			delete(pending, res.attr)
 			if res.err != nil {
-				finalError = res.err
-			}
-			if res.age != nil {
-				person.Age = res.age
-				log.Debug().Int("age", *res.age).Str("name", person.Name).Msg("Age enriched")
-			}
-			if res.gender != nil {
-				person.Gender = res.gender
-				log.Debug().Str("gender", *res.gender).Str("name", person.Name).Msg("Gender enriched")
+				errs = append(errs, fmt.Errorf("%s: %w", res.attr, res.err))
+				continue
 			}
-			if res.nationality != nil {
-				person.Nationality = res.nationality
-				log.Debug().Str("nationality", *res.nationality).Str("name", person.Name).Msg("Nationality enriched")
+			*res.attr.state(&state) = attributeState(res.value != nil)
+			if res.value != nil {
+				res.attr.copyValue(person, res.value)
+				*res.attr.source(&person.AttributeSources) = entity.SourceEnriched
+				log.Debug().
+					Str("attribute", string(res.attr)).
+					Str("provider", res.provider).
+					Str("name", person.Name).
+					Msg("Attribute enriched")
 			}
 		}
 	}
 
+	finalError := errors.Join(errs...)
 	if finalError != nil {
-		log.Warn().Err(finalError).Str("name", person.Name).Msg("Enrichment completed with errors")
+		log.Warn().Err(finalError).Strs("failed", state.Failed()).Str("name", person.Name).Msg("Enrichment completed with errors")
 	} else {
 		log.Info().Str("name", person.Name).Msg("Enrichment completed successfully")
 	}
 
-	return finalError
+	return state, finalError
+}
+
+// resolveAttribute опрашивает цепочку провайдеров по порядку до первого
+// найденного значения и возвращает имя ответившего провайдера и запись с
+// заполненным атрибутом. Провайдеры получают ФИО person. Если значения нет,
+// но кто-то из провайдеров ответил ошибкой, возвращается ошибка: отсутствие
+// данных у запасного провайдера не означает, что их нет у основного.
+//
+// Оставшееся до дедлайна ctx время делится поровну между ещё не опрошенными
+// провайдерами, чтобы зависший основной провайдер не оставил запасным
+// времени: в цепочке api,dataset API получает половину, а справочник — всё,
+// что осталось.
+func resolveAttribute(ctx context.Context, attr Attribute, chain []Provider, person *entity.Person) (string, *entity.Person, error) {
+	var errs []error
+	for i, p := range chain {
+		value := &entity.Person{Name: person.Name, Surname: person.Surname, Patronymic: person.Patronymic}
+		providerCtx, cancel := providerContext(ctx, len(chain)-i)
+		found, err := p.Enrich(providerCtx, attr, value)
+		cancel()
+		if err != nil {
+			if len(chain) > 1 {
+				log.Warn().Err(err).Str("provider", p.Name()).Str("attribute", string(attr)).Str("name", person.Name).Msg("Enrichment provider failed")
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+			continue
+		}
+		if found {
+			return p.Name(), value, nil
+		}
+	}
+	return "", nil, errors.Join(errs...)
+}
+
+// providerContext выделяет провайдеру долю оставшегося времени ctx, если
+// после него в цепочке остаётся left-1 провайдеров.
+func providerContext(ctx context.Context, left int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || left <= 1 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(left))
+}
+
+func attributeState(found bool) entity.AttributeState {
+	if found {
+		return entity.AttributeOK
+	}
+	return entity.AttributeMissing
+}
+
+// enrichPerson обогащает запись и применяет политику частичного обогащения:
+// в режиме strict любая ошибка прерывает операцию, в режиме partial
+// полученные атрибуты сохраняются, а несостоявшиеся отмечаются в
+// person.EnrichmentState.
+func (s *PersonService) enrichPerson(ctx context.Context, person *entity.Person) error {
+	state, err := enrichFromProviders(ctx, s.providers, person)
+	if err := s.applyEnrichment(person, state, err); err != nil {
+		return fmt.Errorf("%w: %w", ErrEnrichmentFailed, err)
+	}
+	return nil
+}
+
+// reenrichAttributes заново обогащает атрибуты attrs записи person, сохраняя
+// состояние остальных атрибутов. Политика частичного обогащения применяется
+// как в enrichPerson.
+func (s *PersonService) reenrichAttributes(ctx context.Context, person *entity.Person, attrs []Attribute) error {
+	state, err := enrichAttributes(ctx, s.providers, person, attrs)
+	merged := person.EnrichmentState
+	for _, attr := range attrs {
+		*attr.state(&merged) = *attr.state(&state)
+	}
+	if err := s.applyEnrichment(person, merged, err); err != nil {
+		return fmt.Errorf("%w: %w", ErrEnrichmentFailed, err)
+	}
+	return nil
+}
+
+// EnrichmentErrorStatus подбирает HTTP-статус ответа для ошибки обогащения:
+// 422 — внешний API отклонил имя, 503 — исчерпан лимит запросов или открыт
+// circuit breaker, 504 — API не ответил вовремя, 502 — прочие сбои API.
+func EnrichmentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, client.ErrBadRequest):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, client.ErrRateLimited), errors.Is(err, client.ErrCircuitOpen):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// RetryAfter возвращает, через сколько внешний API снимет ограничение
+// частоты запросов, если ошибка вызвана им.
+func RetryAfter(err error) time.Duration {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && !apiErr.RateLimitReset.IsZero() {
+		return max(time.Until(apiErr.RateLimitReset), 0)
+	}
+	return 0
+}
+
+// applyEnrichment записывает в person итог обогащения согласно политике.
+func (s *PersonService) applyEnrichment(person *entity.Person, state entity.EnrichmentState, err error) error {
+	if err != nil && s.cfg.EnrichmentPolicy == entity.EnrichmentPolicyStrict {
+		return err
+	}
+	person.EnrichmentState = state
+	person.EnrichmentStatus = state.Status()
+	person.EnrichmentError = nil
+	if err != nil {
+		msg := err.Error()
+		person.EnrichmentError = &msg
+	}
+	if person.EnrichmentStatus != entity.EnrichmentFailed {
+		now := time.Now()
+		person.EnrichedAt = &now
+	}
+	return nil
+}
+
+// keepPreviousEnrichment переносит из prev значения атрибутов, которые при
+// повторном обогащении не удалось получить из-за ошибки провайдера или
+// которые не обогащались, потому что отключены: устаревшее значение полезнее
+// пустого. Заданные вручную значения переносятся всегда.
+func keepPreviousEnrichment(prev, enriched *entity.Person) {
+	for _, attr := range Attributes {
+		state := attr.state(&enriched.EnrichmentState)
+		switch {
+		case attr.manual(prev):
+			attr.copyValue(enriched, prev)
+			*state = ""
+		case (*state == entity.AttributeError || *state == "") && attr.hasValue(prev):
+			attr.copyValue(enriched, prev)
+			*state = entity.AttributeOK
+		}
+	}
+	enriched.EnrichmentStatus = enriched.EnrichmentState.Status()
+	if enriched.EnrichmentStatus == entity.EnrichmentDone {
+		enriched.EnrichmentError = nil
+	}
+	if enriched.EnrichedAt == nil {
+		enriched.EnrichedAt = prev.EnrichedAt
+	}
+}
+
+// enrichmentTarget возвращает запись, в которую обогащается person заново:
+// только ФИО и заданные вручную атрибуты, которые обогащение пропускает.
+func enrichmentTarget(person *entity.Person) *entity.Person {
+	target := &entity.Person{Name: person.Name, Surname: person.Surname, Patronymic: person.Patronymic}
+	for _, attr := range Attributes {
+		if attr.manual(person) {
+			attr.copyValue(target, person)
+		}
+	}
+	return target
+}
+
+// setManualAttributes записывает в person переданные значения атрибутов как
+// ручные. nil означает, что значение не передано.
+func setManualAttributes(person *entity.Person, age *int, gender, nationality *string) {
+	values := &entity.Person{Age: age, Gender: gender, Nationality: nationality}
+	for _, attr := range Attributes {
+		if attr.hasValue(values) {
+			setManual(person, attr, values)
+		}
+	}
+}
+
+// setManual записывает в person значение атрибута из src как ручное; если
+// значения в src нет, ручное значение снимается. Сопутствующие поля
+// обогащения очищаются, а состояние обогащения атрибута сбрасывается.
+func setManual(person *entity.Person, attr Attribute, src *entity.Person) {
+	attr.copyValue(person, src)
+	if attr.hasValue(src) {
+		*attr.source(&person.AttributeSources) = entity.SourceManual
+	} else {
+		*attr.source(&person.AttributeSources) = ""
+	}
+	*attr.state(&person.EnrichmentState) = ""
+}
+
+// withoutManual исключает из итога обогащения атрибуты, заданные в person
+// вручную: их ошибки не влияют на статус записи и политику strict.
+func withoutManual(person *entity.Person, state entity.EnrichmentState, err error) (entity.EnrichmentState, error) {
+	for _, attr := range Attributes {
+		if attr.manual(person) {
+			*attr.state(&state) = ""
+		}
+	}
+	if len(state.Failed()) == 0 {
+		err = nil
+	}
+	return state, err
+}
+
+// validateAttributes проверяет значения атрибутов, заданные вручную.
+func validateAttributes(age *int, gender, nationality *string) error {
+	if age != nil && *age < 0 {
+		return fmt.Errorf("%w: age cannot be negative", ErrInvalidInput)
+	}
+	if gender != nil && *gender != "male" && *gender != "female" {
+		return fmt.Errorf("%w: gender must be male or female", ErrInvalidInput)
+	}
+	if nationality != nil && *nationality == "" {
+		return fmt.Errorf("%w: nationality cannot be empty", ErrInvalidInput)
+	}
+	return nil
 }
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+// setChangeSource задаёт источник изменений для триггера persons_history до
+// конца транзакции. Без вызова изменения записываются с источником api.
+func setChangeSource(ctx context.Context, tx sqlx.ExecerContext, source entity.ChangeSource) error {
+	_, err := tx.ExecContext(ctx, `SELECT set_config('person_service.change_source', $1, true)`, string(source))
+	if err != nil {
+		log.Error().Err(err).Str("source", string(source)).Msg("Failed to set change source")
+	}
+	return err
+}
+
+// inTx выполняет fn в транзакции с заданным источником изменений.
+func (s *PersonService) inTx(ctx context.Context, source entity.ChangeSource, fn func(tx *sqlx.Tx) error) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start transaction")
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			log.Error().Interface("panic", r).Msg("❌ Rolled back transaction")
+			panic(r)
+		}
+	}()
+
+	if err := setChangeSource(ctx, tx, source); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// GetPersonHistory возвращает журнал изменений записи, новые записи первыми.
+// История мягко удалённых записей доступна полностью; после окончательного
+// удаления от неё остаётся только строка purge без данных.
+func (s *PersonService) GetPersonHistory(id int) ([]entity.PersonHistoryEntry, error) {
+	log.Debug().
+		Int("id", id).
+		Msg("Fetching person history")
+
+	history := make([]entity.PersonHistoryEntry, 0)
+	err := s.db.Select(&history, `
+		SELECT id, person_id, operation, source, changed_at, old_data, new_data
+		FROM persons_history
+		WHERE person_id = $1
+		ORDER BY changed_at DESC, id DESC
+	`, id)
+	if err != nil {
+		log.Error().Err(err).Msg("❌ Failed to fetch person history")
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, ErrNotFound
+	}
+	return history, nil
+}
+
+// GetPersonAsOf восстанавливает состояние записи на момент asOf по журналу
+// изменений. Возвращает ErrNotFound, если запись в тот момент ещё не была
+// создана или уже была окончательно удалена.
+func (s *PersonService) GetPersonAsOf(id int, asOf time.Time) (*entity.Person, error) {
+	log.Debug().
+		Int("id", id).
+		Time("as_of", asOf).
+		Msg("Reconstructing person state")
+
+	var snapshot []byte
+	err := s.db.Get(&snapshot, `
+		SELECT new_data
+		FROM persons_history
+		WHERE person_id = $1 AND changed_at <= $2
+		ORDER BY changed_at DESC, id DESC
+		LIMIT 1
+	`, id, asOf)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		log.Error().Err(err).Msg("❌ Failed to reconstruct person state")
+		return nil, err
+	}
+	// new_data пуст только у операции purge; после неё истории нет.
+	if snapshot == nil {
+		return nil, ErrNotFound
+	}
+
+	var person entity.Person
+	if err := json.Unmarshal(snapshot, &person); err != nil {
+		return nil, fmt.Errorf("failed to decode history snapshot: %w", err)
+	}
+	return &person, nil
+}
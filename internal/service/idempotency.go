@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+// ErrIdempotencyKeyReused возвращается, если ключ уже использовался
+// с другим телом запроса или в другом режиме обогащения.
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request body or enrichment mode")
+
+const maxIdempotencyKeyLength = 255
+
+type idempotencyRecord struct {
+	Fingerprint string          `db:"fingerprint"`
+	StatusCode  sql.NullInt64   `db:"status_code"`
+	Response    json.RawMessage `db:"response"`
+}
+
+// requestFingerprint считает отпечаток тела запроса и режима обогащения, по
+// которому повторный запрос отличается от переиспользования ключа с другими
+// данными: ответы синхронного (201) и асинхронного (202) создания разные.
+func requestFingerprint(input *entity.CreatePersonInput, async bool) (string, error) {
+	body, err := json.Marshal(struct {
+		Input *entity.CreatePersonInput `json:"input"`
+		Async bool                      `json:"async"`
+	}{input, async})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CreatePersonIdempotent создаёт человека, запоминая результат под ключом
+// идемпотентности, и возвращает его вместе с HTTP-статусом ответа.
+// Повторный запрос с тем же ключом и телом возвращает сохранённый ответ и
+// исходный статус (replayed == true) без новой вставки и без обращения к
+// внешним API. Конкурентные запросы с одним ключом сериализуются на
+// первичном ключе таблицы idempotency_keys.
+func (s *PersonService) CreatePersonIdempotent(key string, input *entity.CreatePersonInput, async bool) (person *entity.Person, status int, replayed bool, err error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, 0, false, fmt.Errorf("%w: idempotency key is longer than %d characters", ErrInvalidInput, maxIdempotencyKeyLength)
+	}
+	fingerprint, err := requestFingerprint(input, async)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start transaction")
+		return nil, 0, false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			log.Error().Interface("panic", r).Msg("❌ Rolled back transaction")
+			panic(r)
+		}
+	}()
+
+	// Просроченный ключ считается свободным.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND expires_at < NOW()`, key); err != nil {
+		tx.Rollback()
+		return nil, 0, false, err
+	}
+
+	// Резервируем ключ. Если его держит незавершённая транзакция, INSERT
+	// дождётся её окончания, поэтому второй запрос не вызовет внешние API.
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (key, fingerprint, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		ON CONFLICT (key) DO NOTHING
+	`, key, fingerprint, s.cfg.IdempotencyKeyTTL.Seconds())
+	if err != nil {
+		tx.Rollback()
+		log.Error().Err(err).Msg("Failed to reserve idempotency key")
+		return nil, 0, false, err
+	}
+	reserved, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, false, err
+	}
+
+	if reserved == 0 {
+		tx.Rollback()
+		return s.replayIdempotent(ctx, key, fingerprint)
+	}
+
+	person, err = s.createPersonTx(ctx, tx, input, async)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, false, err
+	}
+
+	response, err := json.Marshal(person)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, false, err
+	}
+	status = http.StatusCreated
+	if person.EnrichmentStatus == entity.EnrichmentPending {
+		status = http.StatusAccepted
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET person_id = $2, status_code = $3, response = $4
+		WHERE key = $1
+	`, key, person.ID, status, response); err != nil {
+		tx.Rollback()
+		log.Error().Err(err).Msg("Failed to store idempotent response")
+		return nil, 0, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, false, fmt.Errorf("commit failed: %w", err)
+	}
+	return person, status, false, nil
+}
+
+func (s *PersonService) replayIdempotent(ctx context.Context, key, fingerprint string) (*entity.Person, int, bool, error) {
+	var rec idempotencyRecord
+	err := s.db.GetContext(ctx, &rec, `
+		SELECT fingerprint, status_code, response FROM idempotency_keys WHERE key = $1
+	`, key)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to load idempotency key")
+		return nil, 0, false, err
+	}
+	if rec.Fingerprint != fingerprint {
+		log.Warn().Str("key", key).Msg("Idempotency key reused with a different body or enrichment mode")
+		return nil, 0, false, ErrIdempotencyKeyReused
+	}
+	if !rec.StatusCode.Valid {
+		// Запись зарезервирована, но ответ не сохранён — не должно происходить,
+		// т.к. резерв и ответ пишутся в одной транзакции.
+		return nil, 0, false, fmt.Errorf("idempotency key %q has no stored response", key)
+	}
+
+	var person entity.Person
+	if err := json.Unmarshal(rec.Response, &person); err != nil {
+		return nil, 0, false, fmt.Errorf("failed to decode stored response: %w", err)
+	}
+
+	log.Info().Str("key", key).Int("id", person.ID).Msg("Replaying idempotent create")
+	return &person, int(rec.StatusCode.Int64), true, nil
+}
+
+// PurgeExpiredIdempotencyKeys удаляет просроченные ключи идемпотентности.
+func (s *PersonService) PurgeExpiredIdempotencyKeys() (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to purge expired idempotency keys")
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RunIdempotencySweeper периодически удаляет просроченные ключи, пока ctx не отменён.
+func (s *PersonService) RunIdempotencySweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.PurgeExpiredIdempotencyKeys()
+			if err != nil {
+				continue
+			}
+			if n > 0 {
+				log.Info().Int64("count", n).Msg("Purged expired idempotency keys")
+			}
+		}
+	}
+}
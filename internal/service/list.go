@@ -0,0 +1,157 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+// applyPersonFilter добавляет к запросу условия фильтра. Используется и для
+// выборки страницы, и для подсчёта total, чтобы условия не расходились.
+func applyPersonFilter(qb squirrel.SelectBuilder, filter entity.PersonFilter) squirrel.SelectBuilder {
+	if !filter.IncludeDeleted {
+		qb = qb.Where(squirrel.Eq{"deleted_at": nil})
+	}
+	if filter.Query != nil {
+		// Операторы %> используют GIN-индексы из миграции 000004: выражения
+		// должны совпадать с индексными дословно.
+		qb = qb.Where(
+			"(person_search_text(name, surname, patronymic) %> lower(?)"+
+				" OR translit_ru(person_search_text(name, surname, patronymic)) %> translit_ru(?))",
+			*filter.Query, *filter.Query,
+		)
+	}
+	if filter.Name != nil {
+		qb = qb.Where(squirrel.ILike{"name": "%" + *filter.Name + "%"})
+	}
+	if filter.Surname != nil {
+		qb = qb.Where(squirrel.ILike{"surname": "%" + *filter.Surname + "%"})
+	}
+	if filter.Patronymic != nil {
+		qb = qb.Where(squirrel.ILike{"patronymic": "%" + *filter.Patronymic + "%"})
+	}
+	if filter.Gender != nil {
+		qb = qb.Where(squirrel.Eq{"gender": *filter.Gender})
+	}
+	if filter.Nationality != nil {
+		qb = qb.Where(squirrel.Eq{"nationality": *filter.Nationality})
+	}
+	if filter.MinAge != nil {
+		qb = qb.Where(squirrel.GtOrEq{"age": *filter.MinAge})
+	}
+	if filter.MaxAge != nil {
+		qb = qb.Where(squirrel.LtOrEq{"age": *filter.MaxAge})
+	}
+	if filter.EnrichmentStatus != nil {
+		qb = qb.Where(squirrel.Eq{"enrichment_status": *filter.EnrichmentStatus})
+	}
+	if filter.MinGenderProbability != nil {
+		qb = qb.Where(squirrel.GtOrEq{"gender_probability": *filter.MinGenderProbability})
+	}
+	if filter.MinNationalityProbability != nil {
+		// Выражение совпадает с индексом idx_persons_nationality_probability.
+		qb = qb.Where("((nationalities->0->>'probability')::double precision) >= ?", *filter.MinNationalityProbability)
+	}
+	if filter.MinAgeSampleCount != nil {
+		qb = qb.Where(squirrel.GtOrEq{"age_sample_count": *filter.MinAgeSampleCount})
+	}
+	if filter.MissingAttributes {
+		qb = qb.Where(squirrel.Or{
+			squirrel.Eq{"age": nil},
+			squirrel.Eq{"gender": nil},
+			squirrel.Eq{"nationality": nil},
+		})
+	}
+	return qb
+}
+
+// searchScoreColumn возвращает выражение релевантности для поиска по q:
+// лучшая из похожестей по исходному написанию и по транслитерации.
+func searchScoreColumn(q string) squirrel.Sqlizer {
+	return squirrel.Expr(
+		"GREATEST("+
+			"word_similarity(lower(?), person_search_text(name, surname, patronymic)), "+
+			"word_similarity(translit_ru(?), translit_ru(person_search_text(name, surname, patronymic)))"+
+			") AS score",
+		q, q,
+	)
+}
+
+// countPersons возвращает общее число записей по фильтру в зависимости от
+// filter.Count. Для CountNone возвращается nil.
+func countPersons(db sqlx.Queryer, filter entity.PersonFilter) (*int64, error) {
+	switch filter.Count {
+	case entity.CountNone:
+		return nil, nil
+
+	case entity.CountEstimated:
+		// Оценка берётся из плана запроса и не требует сканирования таблицы.
+		query, args, err := applyPersonFilter(squirrel.Select("*").From("persons").PlaceholderFormat(squirrel.Dollar), filter).ToSql()
+		if err != nil {
+			return nil, err
+		}
+		var raw []byte
+		if err := sqlx.Get(db, &raw, "EXPLAIN (FORMAT JSON) "+query, args...); err != nil {
+			log.Error().Err(err).Msg("Failed to estimate persons count")
+			return nil, err
+		}
+		var plan []struct {
+			Plan struct {
+				Rows float64 `json:"Plan Rows"`
+			} `json:"Plan"`
+		}
+		if err := json.Unmarshal(raw, &plan); err != nil || len(plan) == 0 {
+			return nil, fmt.Errorf("failed to parse query plan: %v", err)
+		}
+		total := int64(plan[0].Plan.Rows)
+		return &total, nil
+
+	default:
+		query, args, err := applyPersonFilter(squirrel.Select("COUNT(*)").From("persons").PlaceholderFormat(squirrel.Dollar), filter).ToSql()
+		if err != nil {
+			return nil, err
+		}
+		var total int64
+		if err := sqlx.Get(db, &total, query, args...); err != nil {
+			log.Error().Err(err).Msg("Failed to count persons")
+			return nil, err
+		}
+		return &total, nil
+	}
+}
+
+// personOrderBy переводит поля сортировки в выражения ORDER BY. В конец
+// всегда добавляется id, чтобы порядок был детерминированным при равных
+// значениях.
+func personOrderBy(sort []entity.SortField) ([]string, error) {
+	if len(sort) == 0 {
+		return []string{"created_at DESC", "id DESC"}, nil
+	}
+
+	orderBy := make([]string, 0, len(sort)+1)
+	hasID := false
+	for _, f := range sort {
+		column, ok := entity.PersonSortColumns[f.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidInput, f.Field)
+		}
+		if column == "id" {
+			hasID = true
+		}
+		// NULL-значения (не обогащённые поля) всегда в конце списка.
+		if f.Desc {
+			orderBy = append(orderBy, column+" DESC NULLS LAST")
+		} else {
+			orderBy = append(orderBy, column+" ASC NULLS LAST")
+		}
+	}
+	if !hasID {
+		orderBy = append(orderBy, "id ASC")
+	}
+	return orderBy, nil
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+// ProviderPatronymic — имя провайдера, определяющего пол по окончаниям
+// отчества и фамилии.
+const ProviderPatronymic = "patronymic"
+
+// Достоверность пола, определённого по правилам.
+const (
+	// patronymicConfidence — по отчеству, фамилия согласуется или не
+	// распознана.
+	patronymicConfidence = 0.99
+	// conflictConfidence — по отчеству, но фамилия указывает на другой пол.
+	conflictConfidence = 0.8
+	// surnameConfidence — только по фамилии (в кириллице): окончания
+	// -ов/-ова встречаются и у несклоняемых фамилий, поэтому доверие ниже.
+	surnameConfidence = 0.9
+)
+
+// genderSuffix — окончание, указывающее на пол.
+type genderSuffix struct {
+	suffix string
+	gender string
+}
+
+// Окончания отчеств, включая тюркские «оглы»/«кызы». Латинские варианты
+// покрывают распространённую транслитерацию. Короткое «-ich» в латинице
+// не используется: им оканчиваются и немецкие имена (Friedrich, Heinrich),
+// поэтому из отчеств на «-ич» без «-ов-/-ев-» распознаётся только «-yich»
+// (Ilyich).
+var patronymicSuffixes = []genderSuffix{
+	{"овна", "female"}, {"евна", "female"}, {"ична", "female"}, {"кызы", "female"}, {"гызы", "female"},
+	{"ovna", "female"}, {"evna", "female"}, {"ichna", "female"}, {"kyzy", "female"}, {"qizi", "female"},
+	{"ович", "male"}, {"евич", "male"}, {"ич", "male"}, {"оглы", "male"}, {"улы", "male"},
+	{"ovich", "male"}, {"evich", "male"}, {"yich", "male"}, {"ogly", "male"}, {"uly", "male"},
+}
+
+var surnameSuffixes = []genderSuffix{
+	{"ова", "female"}, {"ева", "female"}, {"ёва", "female"}, {"ина", "female"}, {"ына", "female"},
+	{"ская", "female"}, {"цкая", "female"},
+	{"ов", "male"}, {"ев", "male"}, {"ёв", "male"}, {"ин", "male"}, {"ын", "male"},
+	{"ский", "male"}, {"цкий", "male"}, {"ской", "male"},
+}
+
+// Латинские окончания фамилий встречаются и в неславянских фамилиях
+// (Casanova, Terranova), поэтому учитываются только вместе с распознанным
+// отчеством — для снижения достоверности при расхождении. «-ин»/«-ина» не
+// используются совсем: они часты в фамилиях вроде Martin и Lin.
+var latinSurnameSuffixes = []genderSuffix{
+	{"ova", "female"}, {"eva", "female"}, {"skaya", "female"}, {"tskaya", "female"},
+	{"ov", "male"}, {"ev", "male"}, {"sky", "male"}, {"skiy", "male"}, {"skii", "male"},
+}
+
+// patronymicProvider определяет пол по отчеству и фамилии без обращения к
+// внешним API. Если правила не дают ответа, запись остаётся без данных, и
+// пол определяет следующий провайдер цепочки.
+type patronymicProvider struct{}
+
+// NewPatronymicProvider создаёт провайдер пола по окончаниям отчества
+// (-ович/-овна) и фамилии (-ов/-ова, без отчества — только в кириллице).
+// Вероятность пола отражает надёжность сработавшего правила.
+func NewPatronymicProvider() Provider {
+	return patronymicProvider{}
+}
+
+func (patronymicProvider) Name() string {
+	return ProviderPatronymic
+}
+
+func (patronymicProvider) Supports(attr Attribute) bool {
+	return attr == AttributeGender
+}
+
+func (patronymicProvider) usesFullName() {}
+
+func (patronymicProvider) Enrich(_ context.Context, attr Attribute, person *entity.Person) (bool, error) {
+	if attr != AttributeGender {
+		return false, nil
+	}
+
+	var byPatronymic string
+	if person.Patronymic != nil {
+		byPatronymic = matchSuffix(*person.Patronymic, patronymicSuffixes)
+	}
+	bySurname := matchSuffix(person.Surname, surnameSuffixes)
+	if bySurname == "" && byPatronymic != "" {
+		bySurname = matchSuffix(person.Surname, latinSurnameSuffixes)
+	}
+
+	var gender string
+	var probability float64
+	switch {
+	case byPatronymic != "" && bySurname != "" && bySurname != byPatronymic:
+		gender, probability = byPatronymic, conflictConfidence
+	case byPatronymic != "":
+		gender, probability = byPatronymic, patronymicConfidence
+	case bySurname != "":
+		gender, probability = bySurname, surnameConfidence
+	default:
+		return false, nil
+	}
+
+	person.Gender, person.GenderProbability = &gender, &probability
+	return true, nil
+}
+
+// matchSuffix возвращает пол по первому подходящему окончанию слова. Перед
+// окончанием должно оставаться не меньше двух букв, чтобы короткие слова
+// («Ив», «Лин») не распознавались.
+func matchSuffix(word string, suffixes []genderSuffix) string {
+	word = strings.ToLower(strings.TrimSpace(word))
+	for _, s := range suffixes {
+		if strings.HasSuffix(word, s.suffix) && len([]rune(word))-len([]rune(s.suffix)) >= 2 {
+			return s.gender
+		}
+	}
+	return ""
+}
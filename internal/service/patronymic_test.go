@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+func TestPatronymicProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		surname     string
+		patronymic  string
+		gender      string // "" — правила не дают ответа
+		probability float64
+	}{
+		// Отчество, фамилия согласуется или не распознана.
+		{"cyrillic male", "Иванов", "Петрович", "male", patronymicConfidence},
+		{"cyrillic female", "Иванова", "Петровна", "female", patronymicConfidence},
+		{"cyrillic -ич", "Ульянов", "Ильич", "male", patronymicConfidence},
+		{"cyrillic -ична", "Смит", "Кузьминична", "female", patronymicConfidence},
+		{"turkic male", "Алиев", "Гейдар оглы", "male", patronymicConfidence},
+		{"turkic female", "Smith", "Aliyevna kyzy", "female", patronymicConfidence},
+		{"latin male", "Smith", "Petrovich", "male", patronymicConfidence},
+		{"latin female", "Smith", "Sergeevna", "female", patronymicConfidence},
+		{"latin Ilyich", "Ulyanov", "Ilyich", "male", patronymicConfidence},
+		{"case and spaces", "Smith", "  PETROVICH ", "male", patronymicConfidence},
+
+		// Только фамилия.
+		{"cyrillic surname male", "Петров", "", "male", surnameConfidence},
+		{"cyrillic surname female", "Петрова", "", "female", surnameConfidence},
+		{"cyrillic -ская", "Вишневская", "", "female", surnameConfidence},
+		{"cyrillic -ин", "Пушкин", "", "male", surnameConfidence},
+		{"latin surname alone", "Petrova", "", "", 0},
+
+		// Отчество и фамилия расходятся: побеждает отчество.
+		{"cyrillic conflict", "Иванова", "Петрович", "male", conflictConfidence},
+		{"latin conflict", "Petrov", "Ivanovna", "female", conflictConfidence},
+
+		// Неславянские имена и фамилии.
+		{"Friedrich", "Müller", "Friedrich", "", 0},
+		{"Heinrich", "Schmidt", "Heinrich", "", 0},
+		{"Dietrich", "Bauer", "Dietrich", "", 0},
+		{"Casanova", "Casanova", "", "", 0},
+		{"Terranova", "Terranova", "", "", 0},
+		{"Martin", "Martin", "", "", 0},
+		{"Lin", "Lin", "", "", 0},
+		{"short cyrillic surname", "Ов", "", "", 0},
+		{"no patronymic", "Smith", "", "", 0},
+	}
+
+	p := NewPatronymicProvider()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			person := &entity.Person{Name: "X", Surname: tt.surname}
+			if tt.patronymic != "" {
+				person.Patronymic = &tt.patronymic
+			}
+			found, err := p.Enrich(context.Background(), AttributeGender, person)
+			if err != nil {
+				t.Fatalf("Enrich: %v", err)
+			}
+			if tt.gender == "" {
+				if found {
+					t.Errorf("got %s (%v), want no answer", *person.Gender, *person.GenderProbability)
+				}
+				return
+			}
+			if !found || *person.Gender != tt.gender || *person.GenderProbability != tt.probability {
+				t.Errorf("got found=%v gender=%v probability=%v, want %s (%v)",
+					found, person.Gender, person.GenderProbability, tt.gender, tt.probability)
+			}
+		})
+	}
+}
+
+func TestPatronymicProviderOnlyGender(t *testing.T) {
+	p := NewPatronymicProvider()
+	patronymic := "Петрович"
+	person := &entity.Person{Name: "Иван", Surname: "Иванов", Patronymic: &patronymic}
+	if found, _ := p.Enrich(context.Background(), AttributeAge, person); found || person.Age != nil {
+		t.Error("patronymic provider answered for age")
+	}
+}
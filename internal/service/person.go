@@ -2,19 +2,26 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog/log"
 
-	"github.com/k1lls3x/person-service/internal/client"
 	"github.com/k1lls3x/person-service/internal/entity"
 )
 
-var ErrNotFound = errors.New("person not found")
+var (
+	ErrNotFound     = errors.New("person not found")
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrVersionConflict возвращается, если запись изменилась с момента,
+	// когда клиент получил её ETag.
+	ErrVersionConflict = errors.New("person was modified concurrently")
+)
 
 func deref(s *string) string {
 	if s != nil {
@@ -23,6 +30,14 @@ func deref(s *string) string {
 	return "null"
 }
 
+// sameString сравнивает необязательные строки: nil равен только nil.
+func sameString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 func derefInt(i *int) int {
 	if i != nil {
 		return *i
@@ -30,32 +45,112 @@ func derefInt(i *int) int {
 	return 0
 }
 
+// Config содержит настраиваемые параметры сервиса. Нулевые значения
+// заменяются значениями по умолчанию.
+type Config struct {
+	IdempotencyKeyTTL      time.Duration
+	BatchEnrichConcurrency int
+	BatchMaxSize           int
+	// SearchSimilarityThreshold — минимальная word_similarity для поиска по q.
+	SearchSimilarityThreshold float64
+	// PurgeRetention — сколько хранятся мягко удалённые записи до очистки.
+	PurgeRetention time.Duration
+	// PurgeEraseHistory — стирать ли при очистке историю изменений записи.
+	PurgeEraseHistory bool
+
+	// EnrichmentPolicy — partial (по умолчанию) сохраняет полученные
+	// атрибуты при частичном сбое, strict отклоняет операцию целиком.
+	EnrichmentPolicy entity.EnrichmentPolicy
+	// AsyncEnrichment делает асинхронное создание режимом по умолчанию.
+	AsyncEnrichment bool
+	// EnrichMaxAttempts — сколько раз фоновое обогащение повторяется до статуса failed.
+	EnrichMaxAttempts int
+	// EnrichPollInterval — как часто свободный воркер проверяет очередь.
+	EnrichPollInterval time.Duration
+	// EnrichLockTimeout — через сколько задача в статусе running считается
+	// брошенной (например, после перезапуска) и забирается заново.
+	EnrichLockTimeout time.Duration
+
+	// EnrichStaleAfter — через сколько обогащённая запись считается
+	// устаревшей и обогащается sweeper'ом заново.
+	EnrichStaleAfter time.Duration
+	// EnrichRetryAfter — пауза перед повторным обогащением записи с
+	// недостающими атрибутами, чтобы не опрашивать API при каждом проходе.
+	EnrichRetryAfter time.Duration
+	// EnrichSweepBatch — сколько записей sweeper берёт за один проход.
+	EnrichSweepBatch int
+	// EnrichSweepConcurrency — сколько записей sweeper обогащает параллельно.
+	EnrichSweepConcurrency int
+	// EnrichSweepRate — не более стольких обогащений в секунду у sweeper'а.
+	EnrichSweepRate float64
+}
+
+func (c Config) withDefaults() Config {
+	if c.IdempotencyKeyTTL <= 0 {
+		c.IdempotencyKeyTTL = 24 * time.Hour
+	}
+	if c.BatchEnrichConcurrency <= 0 {
+		c.BatchEnrichConcurrency = 8
+	}
+	if c.BatchMaxSize <= 0 {
+		c.BatchMaxSize = 500
+	}
+	if c.SearchSimilarityThreshold <= 0 || c.SearchSimilarityThreshold > 1 {
+		c.SearchSimilarityThreshold = 0.3
+	}
+	if c.PurgeRetention <= 0 {
+		c.PurgeRetention = 30 * 24 * time.Hour
+	}
+	if c.EnrichmentPolicy != entity.EnrichmentPolicyStrict {
+		c.EnrichmentPolicy = entity.EnrichmentPolicyPartial
+	}
+	if c.EnrichMaxAttempts <= 0 {
+		c.EnrichMaxAttempts = 5
+	}
+	if c.EnrichPollInterval <= 0 {
+		c.EnrichPollInterval = time.Second
+	}
+	if c.EnrichLockTimeout <= 0 {
+		c.EnrichLockTimeout = time.Minute
+	}
+	if c.EnrichStaleAfter <= 0 {
+		c.EnrichStaleAfter = 30 * 24 * time.Hour
+	}
+	if c.EnrichRetryAfter <= 0 {
+		c.EnrichRetryAfter = 24 * time.Hour
+	}
+	if c.EnrichSweepBatch <= 0 {
+		c.EnrichSweepBatch = 100
+	}
+	if c.EnrichSweepConcurrency <= 0 {
+		c.EnrichSweepConcurrency = 2
+	}
+	if c.EnrichSweepRate <= 0 {
+		c.EnrichSweepRate = 1
+	}
+	return c
+}
+
 type PersonService struct {
 	db        *sqlx.DB
-	apiClient *client.APIClient
+	providers *Registry
+	cfg       Config
 }
 
-func NewPersonService(db *sqlx.DB, apiClient *client.APIClient) *PersonService {
-	return &PersonService{db: db, apiClient: apiClient}
+func NewPersonService(db *sqlx.DB, providers *Registry, cfg Config) *PersonService {
+	return &PersonService{db: db, providers: providers, cfg: cfg.withDefaults()}
 }
 
-// CreatePerson godoc
-// @Summary Создать нового человека
-// @Description Добавляет человека с обогащением через внешние API
-// @Tags persons
-// @Accept json
-// @Produce json
-// @Param person body entity.Person true "Персона"
-// @Success 201 {object} entity.Person
-// @Failure 400 {string} string "bad request"
-// @Failure 500 {string} string "server error"
-// @Router /api/persons [post]
-func (s *PersonService) CreatePerson(input *entity.CreatePersonInput) (*entity.Person, error) {
-	person := &entity.Person{
-		Name:       input.Name,
-		Surname:    input.Surname,
-		Patronymic: input.Patronymic,
-	}
+// AsyncEnrichmentDefault сообщает, создаются ли записи асинхронно, если
+// клиент не указал режим явно.
+func (s *PersonService) AsyncEnrichmentDefault() bool {
+	return s.cfg.AsyncEnrichment
+}
+
+// CreatePerson добавляет человека с обогащением через внешние API. При
+// async == true запись сохраняется сразу со статусом pending, а обогащение
+// выполняют фоновые воркеры (см. RunEnrichmentWorkers).
+func (s *PersonService) CreatePerson(input *entity.CreatePersonInput, async bool) (*entity.Person, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -73,14 +168,54 @@ func (s *PersonService) CreatePerson(input *entity.CreatePersonInput) (*entity.P
 		}
 	}()
 
+	person, err := s.createPersonTx(ctx, tx, input, async)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit failed: %w", err)
+	}
+	return person, nil
+}
+
+// createPersonTx обогащает и вставляет запись в рамках переданной транзакции.
+// В асинхронном режиме вместо обогащения ставит задачу в очередь.
+// Откат транзакции при ошибке остаётся на вызывающей стороне.
+func (s *PersonService) createPersonTx(ctx context.Context, tx *sqlx.Tx, input *entity.CreatePersonInput, async bool) (*entity.Person, error) {
+	if err := validateAttributes(input.Age, input.Gender, input.Nationality); err != nil {
+		return nil, err
+	}
+	person := &entity.Person{
+		Name:       input.Name,
+		Surname:    input.Surname,
+		Patronymic: input.Patronymic,
+	}
+	setManualAttributes(person, input.Age, input.Gender, input.Nationality)
+
+	if async {
+		person.EnrichmentStatus = entity.EnrichmentPending
+		if err := insertPerson(ctx, tx, person); err != nil {
+			return nil, err
+		}
+		if err := enqueueEnrichment(ctx, tx, person.ID); err != nil {
+			return nil, err
+		}
+		log.Info().
+			Int("id", person.ID).
+			Str("name", person.Name).
+			Msg("Person created, enrichment queued")
+		return person, nil
+	}
+
 	log.Debug().
 		Str("name", person.Name).
 		Str("surname", person.Surname).
 		Msg("Starting person enrichment")
 
-	if err := enrichFromAPI(ctx, s.apiClient, person); err != nil {
+	if err := s.enrichPerson(ctx, person); err != nil {
 		log.Error().Err(err).Msg("Failed to enrich person from API")
-		tx.Rollback()
 		return nil, fmt.Errorf("failed to enrich person: %w", err)
 	}
 
@@ -90,49 +225,65 @@ func (s *PersonService) CreatePerson(input *entity.CreatePersonInput) (*entity.P
 		Str("gender", deref(person.Gender)).
 		Int("age", derefInt(person.Age)).
 		Str("nationality", deref(person.Nationality)).
-		Msg("Person enriched successfully")
+		Str("status", string(person.EnrichmentStatus)).
+		Msg("Person enriched")
 
+	if err := insertPerson(ctx, tx, person); err != nil {
+		return nil, err
+	}
+	return person, nil
+}
+
+// insertPerson вставляет уже обогащённую запись и дописывает в неё
+// сгенерированные базой поля. Работает как с *sqlx.DB, так и с *sqlx.Tx.
+func insertPerson(ctx context.Context, db sqlx.ExtContext, person *entity.Person) error {
 	log.Debug().Msg("Inserting person into database")
 
+	if person.EnrichmentStatus == "" {
+		person.EnrichmentStatus = entity.EnrichmentDone
+	}
+
 	query := `
-				INSERT INTO persons (name, surname, patronymic, age, gender, nationality)
-				VALUES (:name, :surname, :patronymic, :age, :gender, :nationality)
-				RETURNING id, created_at
+				INSERT INTO persons (name, surname, patronymic, age, gender, nationality,
+					age_sample_count, gender_probability, nationalities, attribute_sources,
+					enrichment_status, enrichment_error, enrichment_state, enriched_at)
+				VALUES (:name, :surname, :patronymic, :age, :gender, :nationality,
+					:age_sample_count, :gender_probability, :nationalities, :attribute_sources,
+					:enrichment_status, :enrichment_error, :enrichment_state, :enriched_at)
+				RETURNING id, created_at, updated_at, version
 		`
 
-	rows, err := tx.NamedQuery(query, person)
+	rows, err := sqlx.NamedQueryContext(ctx, db, query, person)
 	if err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to insert person: %w", err)
+		return fmt.Errorf("failed to insert person: %w", err)
 	}
 	defer rows.Close()
 
 	if !rows.Next() {
-		tx.Rollback()
-		return nil, fmt.Errorf("no rows returned")
+		return fmt.Errorf("no rows returned")
 	}
 
 	if err := rows.StructScan(person); err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to scan returned values: %w", err)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("commit failed: %w", err)
+		return fmt.Errorf("failed to scan returned values: %w", err)
 	}
-	return person, nil
+	return nil
 }
 
-func (s *PersonService) DeletePersonById(id int) error {
+// DeletePersonById мягко удаляет запись, проставляя deleted_at. Если
+// expectedVersion не nil, удаление выполняется только при совпадении версии,
+// иначе возвращается ErrVersionConflict.
+func (s *PersonService) DeletePersonById(id int, expectedVersion *int) error {
 	log.Debug().
 		Int("id", id).
 		Msg("Starting deleting person by id")
 
 	query := `
-		DELETE FROM persons WHERE id = $1
+		UPDATE persons
+		SET deleted_at = NOW(), updated_at = NOW(), version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($2::int IS NULL OR version = $2)
 	`
 
-	result, err := s.db.Exec(query, id)
+	result, err := s.db.Exec(query, id, expectedVersion)
 	if err != nil {
 		log.Error().Err(err).Msg("❌ Failed to delete person by id")
 		return err
@@ -145,6 +296,19 @@ func (s *PersonService) DeletePersonById(id int) error {
 	}
 
 	if rowsAffected == 0 {
+		if expectedVersion != nil {
+			var exists bool
+			if err := s.db.Get(&exists, `SELECT EXISTS(SELECT 1 FROM persons WHERE id = $1 AND deleted_at IS NULL)`, id); err != nil {
+				return err
+			}
+			if exists {
+				log.Warn().
+					Int("id", id).
+					Int("expected_version", *expectedVersion).
+					Msg("Version mismatch on delete")
+				return ErrVersionConflict
+			}
+		}
 		log.Warn().
 			Int("id", id).
 			Msg("No person found to delete")
@@ -158,73 +322,153 @@ func (s *PersonService) DeletePersonById(id int) error {
 	return nil
 }
 
-// GetPersons godoc
-// @Summary Получить список людей с фильтрами и пагинацией
-// @Tags persons
-// @Accept json
-// @Produce json
-// @Param name query string false "Имя"
-// @Param surname query string false "Фамилия"
-// @Param gender query string false "Пол"
-// @Param nationality query string false "Национальность"
-// @Param minAge query int false "Мин. возраст"
-// @Param maxAge query int false "Макс. возраст"
-// @Param page query int false "Страница"
-// @Param pageSize query int false "Размер страницы"
-// @Success 200 {array} entity.Person
-// @Router /api/persons [get]
-func (s *PersonService) GetPersons(filter entity.PersonFilter) ([]entity.Person, error) {
+func (s *PersonService) GetPersonById(id int) (*entity.Person, error) {
+	log.Debug().
+		Int("id", id).
+		Msg("Fetching person by id")
+
+	query := `
+		SELECT * FROM persons WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	var person entity.Person
+	if err := s.db.Get(&person, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn().
+				Int("id", id).
+				Msg("Person not found")
+			return nil, ErrNotFound
+		}
+		log.Error().Err(err).Msg("❌ Failed to fetch person by id")
+		return nil, err
+	}
+
+	return &person, nil
+}
+
+// GetPersons возвращает страницу людей по фильтру. Пагинация — через
+// page/pageSize или, если задан filter.Cursor, через keyset-курсор.
+func (s *PersonService) GetPersons(filter entity.PersonFilter) (*entity.PersonList, error) {
 	log.Debug().Msg("Fetching persons with filters")
 
 	qb := squirrel.Select("*").From("persons").PlaceholderFormat(squirrel.Dollar)
+	if filter.Query != nil {
+		qb = qb.Column(searchScoreColumn(*filter.Query))
+	}
+	qb = applyPersonFilter(qb, filter)
 
-	if filter.Name != nil {
-		qb = qb.Where(squirrel.ILike{"name": "%" + *filter.Name + "%"})
+	if filter.Page <= 0 {
+		filter.Page = 1
 	}
-	if filter.Surname != nil {
-		qb = qb.Where(squirrel.ILike{"surname": "%" + *filter.Surname + "%"})
+	if filter.PageSize <= 0 {
+		filter.PageSize = 10
 	}
-	if filter.Patronymic != nil {
-		qb = qb.Where(squirrel.ILike{"patronymic": "%" + *filter.Patronymic + "%"})
+	if filter.Count == "" {
+		filter.Count = entity.CountExact
 	}
-	if filter.Gender != nil {
-		qb = qb.Where(squirrel.Eq{"gender": *filter.Gender})
+
+	if (len(filter.Sort) > 0 || filter.Query != nil) && filter.Cursor != nil {
+		return nil, fmt.Errorf("%w: cursor pagination supports only the default sort", ErrInvalidInput)
 	}
-	if filter.Nationality != nil {
-		qb = qb.Where(squirrel.Eq{"nationality": *filter.Nationality})
+	orderBy, err := personOrderBy(filter.Sort)
+	if err != nil {
+		return nil, err
 	}
-	if filter.MinAge != nil {
-		qb = qb.Where(squirrel.GtOrEq{"age": *filter.MinAge})
+	if filter.Query != nil && len(filter.Sort) == 0 {
+		// Результаты поиска по умолчанию ранжируются по релевантности.
+		orderBy = []string{"score DESC", "id ASC"}
 	}
-	if filter.MaxAge != nil {
-		qb = qb.Where(squirrel.LtOrEq{"age": *filter.MaxAge})
+
+	qb = qb.OrderBy(orderBy...)
+	if filter.Cursor != nil {
+		if *filter.Cursor != "" {
+			c, err := decodeCursor(*filter.Cursor)
+			if err != nil {
+				return nil, err
+			}
+			// created_at <= t отдельным условием, чтобы планировщик мог
+			// использовать idx_persons_created_at_desc как диапазон.
+			qb = qb.Where(squirrel.LtOrEq{"created_at": c.CreatedAt}).
+				Where(squirrel.Or{
+					squirrel.Lt{"created_at": c.CreatedAt},
+					squirrel.Lt{"id": c.ID},
+				})
+		}
+	} else {
+		offset := (filter.Page - 1) * filter.PageSize
+		qb = qb.Offset(uint64(offset))
 	}
+	// Лишняя строка показывает, есть ли следующая страница.
+	qb = qb.Limit(uint64(filter.PageSize) + 1)
 
-	if filter.Page <= 0 {
-		filter.Page = 1
+	query, args, err := qb.ToSql()
+	if err != nil {
+		return nil, err
 	}
-	if filter.PageSize <= 0 {
-		filter.PageSize = 10
+
+	log.Debug().Str("query", query).Int("args_len", len(args)).Msg("Final SQL query")
+
+	// Выборка и подсчёт идут в одной транзакции: порог похожести для поиска
+	// задаётся на уровне транзакции и должен действовать в обоих запросах.
+	tx, err := s.db.BeginTxx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start transaction")
+		return nil, err
 	}
-	offset := (filter.Page - 1) * filter.PageSize
+	defer tx.Rollback()
 
-	qb = qb.OrderBy("created_at DESC").Limit(uint64(filter.PageSize)).Offset(uint64(offset))
+	if filter.Query != nil {
+		if _, err := tx.Exec(`SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`,
+			strconv.FormatFloat(s.cfg.SearchSimilarityThreshold, 'f', -1, 64)); err != nil {
+			log.Error().Err(err).Msg("Failed to set similarity threshold")
+			return nil, err
+		}
+	}
 
-	query, args, err := qb.ToSql()
+	persons, err := queryPersons(tx, filter.PageSize+1, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Debug().Str("query", query).Int("args_len", len(args)).Msg("Final SQL query")
+	list := &entity.PersonList{
+		Items:    persons,
+		PageSize: filter.PageSize,
+		Count:    filter.Count,
+	}
+	if filter.Cursor == nil {
+		list.Page = filter.Page
+	}
+	if len(persons) > filter.PageSize {
+		list.Items = persons[:filter.PageSize]
+		list.HasMore = true
+		if filter.Cursor != nil {
+			last := list.Items[len(list.Items)-1]
+			next := encodeCursor(personCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+			list.NextCursor = &next
+		}
+	}
 
-	rows, err := s.db.Queryx(query, args...)
+	total, err := countPersons(tx, filter)
+	if err != nil {
+		return nil, err
+	}
+	list.Total = total
+
+	log.Info().Int("count", len(list.Items)).Msg("Persons fetched successfully")
+	return list, nil
+}
+
+// queryPersons выполняет запрос и сканирует все строки. Курсор закрывается
+// до возврата, чтобы в той же транзакции можно было выполнить следующий запрос.
+func queryPersons(q sqlx.Queryer, capacity int, query string, args ...interface{}) ([]entity.Person, error) {
+	rows, err := q.Queryx(query, args...)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to query persons")
 		return nil, err
 	}
 	defer rows.Close()
 
-	var persons []entity.Person
+	persons := make([]entity.Person, 0, capacity)
 	for rows.Next() {
 		var person entity.Person
 		if err := rows.StructScan(&person); err != nil {
@@ -233,23 +477,28 @@ func (s *PersonService) GetPersons(filter entity.PersonFilter) ([]entity.Person,
 		}
 		persons = append(persons, person)
 	}
-
-	log.Info().Int("count", len(persons)).Msg("Persons fetched successfully")
-	return persons, nil
+	return persons, rows.Err()
 }
 
-func (s *PersonService) UpdatePerson(id int, input *entity.UpdatePersonInput) (*entity.Person, error) {
+// UpdatePerson полностью заменяет ФИО и заново обогащает запись. Если
+// expectedVersion не nil, версия проверяется внутри транзакции и при
+// несовпадении возвращается ErrVersionConflict.
+func (s *PersonService) UpdatePerson(id int, input *entity.UpdatePersonInput, expectedVersion *int) (*entity.Person, error) {
+	if err := validateAttributes(input.Age, input.Gender, input.Nationality); err != nil {
+		return nil, err
+	}
 	updatedPerson := &entity.Person{
 		ID:         id,
 		Name:       input.Name,
 		Surname:    input.Surname,
 		Patronymic: input.Patronymic,
 	}
+	setManualAttributes(updatedPerson, input.Age, input.Gender, input.Nationality)
 	log.Debug().Msg("Change person starting")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	tx, err := s.db.Beginx()
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to start transaction")
 		return nil, err
@@ -263,7 +512,12 @@ func (s *PersonService) UpdatePerson(id int, input *entity.UpdatePersonInput) (*
 		}
 	}()
 
-	if err := enrichFromAPI(ctx, s.apiClient, updatedPerson); err != nil {
+	if err := lockVersion(ctx, tx, id, expectedVersion); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := s.enrichPerson(ctx, updatedPerson); err != nil {
 		tx.Rollback()
 		log.Error().Err(err).Msg("Failed to enrich person")
 		return nil, err
@@ -277,18 +531,36 @@ func (s *PersonService) UpdatePerson(id int, input *entity.UpdatePersonInput) (*
 			age = :age,
 			gender = :gender,
 			nationality = :nationality,
-			updated_at = NOW()
-		WHERE id = :id;
+			age_sample_count = :age_sample_count,
+			gender_probability = :gender_probability,
+			nationalities = :nationalities,
+			attribute_sources = :attribute_sources,
+			enrichment_status = :enrichment_status,
+			enrichment_error = :enrichment_error,
+			enrichment_state = :enrichment_state,
+			enriched_at = :enriched_at,
+			updated_at = NOW(),
+			version = version + 1
+		WHERE id = :id
+		RETURNING created_at, updated_at, version;
 	`
 	updatedPerson.ID = id
-	res, err := tx.NamedExecContext(ctx, query, updatedPerson)
+	rows, err := tx.NamedQuery(query, updatedPerson)
 	if err != nil {
 		tx.Rollback()
 		log.Error().Err(err).Msg("Failed to update person")
 		return nil, err
 	}
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
+	found := rows.Next()
+	if found {
+		err = rows.StructScan(updatedPerson)
+	}
+	rows.Close()
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to scan returned values: %w", err)
+	}
+	if !found {
 		tx.Rollback()
 		return nil, ErrNotFound
 	}
@@ -300,6 +572,185 @@ func (s *PersonService) UpdatePerson(id int, input *entity.UpdatePersonInput) (*
 		Int("id", id).
 		Str("name", updatedPerson.Name).
 		Str("surname", updatedPerson.Surname).
+		Int("version", updatedPerson.Version).
 		Msg("Person updated successfully")
 	return updatedPerson, nil
 }
+
+// lockVersion блокирует строку до конца транзакции и сверяет её версию с
+// ожидаемой клиентом.
+func lockVersion(ctx context.Context, tx *sqlx.Tx, id int, expectedVersion *int) error {
+	var version int
+	if err := tx.GetContext(ctx, &version, `SELECT version FROM persons WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		log.Error().Err(err).Int("id", id).Msg("Failed to lock person row")
+		return err
+	}
+	if expectedVersion != nil && *expectedVersion != version {
+		log.Warn().
+			Int("id", id).
+			Int("expected_version", *expectedVersion).
+			Int("actual_version", version).
+			Msg("Version mismatch")
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// PatchPerson применяет JSON Merge Patch (RFC 7396) к записи: меняются только
+// переданные поля, явный null очищает колонку. При смене имени заново
+// обогащаются все атрибуты, кроме явно переданных в патче; при смене фамилии
+// или отчества — атрибуты, чьи провайдеры их учитывают (пол у patronymic);
+// при снятии ручного значения — только этот атрибут.
+func (s *PersonService) PatchPerson(id int, input *entity.PatchPersonInput, expectedVersion *int) (*entity.Person, error) {
+	if input.Name.Set && (input.Name.Value == nil || *input.Name.Value == "") {
+		return nil, fmt.Errorf("%w: name cannot be empty", ErrInvalidInput)
+	}
+	if input.Surname.Set && (input.Surname.Value == nil || *input.Surname.Value == "") {
+		return nil, fmt.Errorf("%w: surname cannot be empty", ErrInvalidInput)
+	}
+	if err := validateAttributes(input.Age.Value, input.Gender.Value, input.Nationality.Value); err != nil {
+		return nil, err
+	}
+
+	log.Debug().Int("id", id).Msg("Patch person starting")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start transaction")
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			log.Error().Interface("panic", r).Msg("Rolled back transaction due to panic")
+			panic(r)
+		}
+	}()
+
+	var person entity.Person
+	if err := tx.GetContext(ctx, &person, `SELECT * FROM persons WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		log.Error().Err(err).Msg("Failed to load person for patch")
+		return nil, err
+	}
+	if expectedVersion != nil && *expectedVersion != person.Version {
+		tx.Rollback()
+		log.Warn().
+			Int("id", id).
+			Int("expected_version", *expectedVersion).
+			Int("actual_version", person.Version).
+			Msg("Version mismatch")
+		return nil, ErrVersionConflict
+	}
+
+	nameChanged := input.Name.Set && *input.Name.Value != person.Name
+	// Провайдеры вроде patronymic определяют атрибут и по фамилии с
+	// отчеством, поэтому их смена тоже делает значение устаревшим.
+	fullNameChanged := nameChanged ||
+		(input.Surname.Set && *input.Surname.Value != person.Surname) ||
+		(input.Patronymic.Set && !sameString(input.Patronymic.Value, person.Patronymic))
+
+	if input.Name.Set {
+		person.Name = *input.Name.Value
+	}
+	if input.Surname.Set {
+		person.Surname = *input.Surname.Value
+	}
+	if input.Patronymic.Set {
+		person.Patronymic = input.Patronymic.Value
+	}
+
+	// Явно переданные атрибуты становятся ручными, их ошибки обогащения
+	// больше не актуальны. Снятое ручное значение заполняется обогащением
+	// сразу.
+	cleared := make(map[Attribute]bool)
+	patch := func(set bool, attr Attribute, value *entity.Person) {
+		if !set {
+			return
+		}
+		if !attr.hasValue(value) && attr.manual(&person) {
+			cleared[attr] = true
+		}
+		setManual(&person, attr, value)
+	}
+	patch(input.Age.Set, AttributeAge, &entity.Person{Age: input.Age.Value})
+	patch(input.Gender.Set, AttributeGender, &entity.Person{Gender: input.Gender.Value})
+	patch(input.Nationality.Set, AttributeNationality, &entity.Person{Nationality: input.Nationality.Value})
+
+	var reenrich []Attribute
+	for _, attr := range s.providers.Enabled() {
+		if attr.manual(&person) {
+			continue
+		}
+		if nameChanged || cleared[attr] || (fullNameChanged && s.providers.usesFullName(attr)) {
+			reenrich = append(reenrich, attr)
+		}
+	}
+	needsEnrichment := len(reenrich) > 0
+
+	if needsEnrichment {
+		if err := s.reenrichAttributes(ctx, &person, reenrich); err != nil {
+			tx.Rollback()
+			log.Error().Err(err).Msg("Failed to enrich person")
+			return nil, err
+		}
+	} else if person.EnrichmentStatus != entity.EnrichmentPending {
+		person.EnrichmentStatus = person.EnrichmentState.Status()
+	}
+
+	query := `
+	UPDATE persons
+		SET
+			name = :name,
+			surname = :surname,
+			patronymic = :patronymic,
+			age = :age,
+			gender = :gender,
+			nationality = :nationality,
+			age_sample_count = :age_sample_count,
+			gender_probability = :gender_probability,
+			nationalities = :nationalities,
+			attribute_sources = :attribute_sources,
+			enrichment_status = :enrichment_status,
+			enrichment_error = :enrichment_error,
+			enrichment_state = :enrichment_state,
+			enriched_at = :enriched_at,
+			updated_at = NOW(),
+			version = version + 1
+		WHERE id = :id
+		RETURNING updated_at, version;
+	`
+	rows, err := tx.NamedQuery(query, &person)
+	if err != nil {
+		tx.Rollback()
+		log.Error().Err(err).Msg("Failed to patch person")
+		return nil, err
+	}
+	if rows.Next() {
+		err = rows.StructScan(&person)
+	}
+	rows.Close()
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to scan returned values: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Int("id", id).
+		Bool("re_enriched", needsEnrichment).
+		Int("version", person.Version).
+		Msg("Person patched successfully")
+	return &person, nil
+}
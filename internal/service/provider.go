@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/k1lls3x/person-service/internal/client"
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+// Attribute — обогащаемый атрибут записи.
+type Attribute string
+
+const (
+	AttributeAge         Attribute = "age"
+	AttributeGender      Attribute = "gender"
+	AttributeNationality Attribute = "nationality"
+)
+
+// Attributes перечисляет все обогащаемые атрибуты.
+var Attributes = []Attribute{AttributeAge, AttributeGender, AttributeNationality}
+
+// state возвращает указатель на состояние атрибута в s.
+func (a Attribute) state(s *entity.EnrichmentState) *entity.AttributeState {
+	switch a {
+	case AttributeAge:
+		return &s.Age
+	case AttributeGender:
+		return &s.Gender
+	default:
+		return &s.Nationality
+	}
+}
+
+// source возвращает указатель на источник значения атрибута в s.
+func (a Attribute) source(s *entity.AttributeSources) *entity.AttributeSource {
+	switch a {
+	case AttributeAge:
+		return &s.Age
+	case AttributeGender:
+		return &s.Gender
+	default:
+		return &s.Nationality
+	}
+}
+
+// manual сообщает, задан ли атрибут person вручную.
+func (a Attribute) manual(person *entity.Person) bool {
+	return *a.source(&person.AttributeSources) == entity.SourceManual
+}
+
+// hasValue сообщает, заполнен ли атрибут в person.
+func (a Attribute) hasValue(person *entity.Person) bool {
+	switch a {
+	case AttributeAge:
+		return person.Age != nil
+	case AttributeGender:
+		return person.Gender != nil
+	default:
+		return person.Nationality != nil
+	}
+}
+
+// copyValue переносит значение атрибута вместе с сопутствующими полями
+// (вероятностью, числом наблюдений) и источником из src в dst.
+func (a Attribute) copyValue(dst, src *entity.Person) {
+	*a.source(&dst.AttributeSources) = *a.source(&src.AttributeSources)
+	switch a {
+	case AttributeAge:
+		dst.Age, dst.AgeSampleCount = src.Age, src.AgeSampleCount
+	case AttributeGender:
+		dst.Gender, dst.GenderProbability = src.Gender, src.GenderProbability
+	default:
+		dst.Nationality, dst.Nationalities = src.Nationality, src.Nationalities
+	}
+}
+
+// Provider — источник значений атрибутов по имени: внешний API, локальный
+// справочник и т. п.
+type Provider interface {
+	// Name — имя провайдера в конфигурации и логах.
+	Name() string
+	// Supports сообщает, умеет ли провайдер определять атрибут.
+	Supports(attr Attribute) bool
+	// Enrich определяет атрибут attr по ФИО person (обычно только по имени) и
+	// записывает значение в person. false без ошибки означает, что данных
+	// нет.
+	Enrich(ctx context.Context, attr Attribute, person *entity.Person) (bool, error)
+}
+
+// fullNameProvider — провайдер, которому кроме имени нужны фамилия и
+// отчество. Ответы остальных провайдеров зависят только от имени.
+type fullNameProvider interface {
+	Provider
+	usesFullName()
+}
+
+// Registry хранит провайдеры и цепочки провайдеров для каждого атрибута.
+// Настраивается при запуске и после этого только читается.
+type Registry struct {
+	providers map[string]Provider
+	chains    map[Attribute][]Provider
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		providers: make(map[string]Provider),
+		chains:    make(map[Attribute][]Provider),
+	}
+}
+
+// Register добавляет провайдер; одноимённый провайдер заменяется.
+func (r *Registry) Register(p Provider) {
+	r.providers[p.Name()] = p
+}
+
+// Use задаёт цепочку провайдеров атрибута. Провайдеры опрашиваются по
+// порядку, пока один из них не найдёт значение. Пустой список отключает
+// обогащение атрибута.
+func (r *Registry) Use(attr Attribute, names ...string) error {
+	chain := make([]Provider, 0, len(names))
+	for _, name := range names {
+		p, ok := r.providers[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown enrichment provider %q", attr, name)
+		}
+		if !p.Supports(attr) {
+			return fmt.Errorf("%s: provider %q does not support the attribute", attr, name)
+		}
+		chain = append(chain, p)
+	}
+	r.chains[attr] = chain
+	return nil
+}
+
+// Chain возвращает цепочку провайдеров атрибута.
+func (r *Registry) Chain(attr Attribute) []Provider {
+	return r.chains[attr]
+}
+
+// usesFullName сообщает, учитывает ли цепочка атрибута фамилию и отчество.
+func (r *Registry) usesFullName(attr Attribute) bool {
+	for _, p := range r.chains[attr] {
+		if _, ok := p.(fullNameProvider); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled возвращает атрибуты, для которых настроен хотя бы один провайдер.
+func (r *Registry) Enabled() []Attribute {
+	var enabled []Attribute
+	for _, attr := range Attributes {
+		if len(r.chains[attr]) > 0 {
+			enabled = append(enabled, attr)
+		}
+	}
+	return enabled
+}
+
+// failedState возвращает состояние, в котором все включённые атрибуты
+// отмечены как ошибочные.
+func (r *Registry) failedState() entity.EnrichmentState {
+	var state entity.EnrichmentState
+	for _, attr := range r.Enabled() {
+		*attr.state(&state) = entity.AttributeError
+	}
+	return state
+}
+
+// Имена встроенных провайдеров.
+const (
+	// ProviderAPI — внешние API agify, genderize и nationalize.
+	ProviderAPI = "api"
+	// ProviderDataset — локальный справочник имён (пакет dataset).
+	ProviderDataset = "dataset"
+)
+
+// fetcherProvider приспосабливает client.Fetcher к интерфейсу Provider.
+type fetcherProvider struct {
+	name    string
+	fetcher client.Fetcher
+}
+
+// NewFetcherProvider создаёт провайдер всех трёх атрибутов поверх
+// client.Fetcher (клиента внешних API, кэша и т. п.).
+func NewFetcherProvider(name string, fetcher client.Fetcher) Provider {
+	return &fetcherProvider{name: name, fetcher: fetcher}
+}
+
+func (p *fetcherProvider) Name() string {
+	return p.name
+}
+
+func (p *fetcherProvider) Supports(Attribute) bool {
+	return true
+}
+
+func (p *fetcherProvider) Enrich(ctx context.Context, attr Attribute, person *entity.Person) (bool, error) {
+	switch attr {
+	case AttributeAge:
+		res, err := p.fetcher.FetchAge(ctx, person.Name)
+		if err != nil || res == nil {
+			return false, err
+		}
+		person.Age, person.AgeSampleCount = &res.Age, &res.Count
+	case AttributeGender:
+		res, err := p.fetcher.FetchGender(ctx, person.Name)
+		// GenderUnknown — имя известно API, но пол не определён: данных нет,
+		// и пол определяет следующий провайдер цепочки.
+		if err != nil || res == nil || res.Gender == client.GenderUnknown {
+			return false, err
+		}
+		person.Gender, person.GenderProbability = &res.Gender, &res.Probability
+	case AttributeNationality:
+		res, err := p.fetcher.FetchNationality(ctx, person.Name)
+		if err != nil || res == nil {
+			return false, err
+		}
+		top := res.Top()
+		person.Nationality = &top.CountryID
+		person.Nationalities = make(entity.Nationalities, len(res.Countries))
+		for i, c := range res.Countries {
+			person.Nationalities[i] = entity.NationalityProbability{CountryID: c.CountryID, Probability: c.Probability}
+		}
+	default:
+		return false, fmt.Errorf("unsupported attribute %q", attr)
+	}
+	return true, nil
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/k1lls3x/person-service/internal/client"
+	"github.com/k1lls3x/person-service/internal/dataset"
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+var errUpstreamDown = errors.New("upstream is down")
+
+// failingProvider имитирует недоступные внешние API.
+type failingProvider struct{}
+
+func (failingProvider) Name() string            { return ProviderAPI }
+func (failingProvider) Supports(Attribute) bool { return true }
+
+func (failingProvider) Enrich(context.Context, Attribute, *entity.Person) (bool, error) {
+	return false, errUpstreamDown
+}
+
+// hangingProvider имитирует внешние API, которые не отвечают до дедлайна.
+type hangingProvider struct{}
+
+func (hangingProvider) Name() string            { return ProviderAPI }
+func (hangingProvider) Supports(Attribute) bool { return true }
+
+func (hangingProvider) Enrich(ctx context.Context, _ Attribute, _ *entity.Person) (bool, error) {
+	<-ctx.Done()
+	return false, ctx.Err()
+}
+
+// fallbackRegistry — цепочка api,dataset для всех атрибутов с недоступными API.
+func fallbackRegistry(t *testing.T) *Registry {
+	t.Helper()
+	return fallbackRegistryWith(t, failingProvider{})
+}
+
+func fallbackRegistryWith(t *testing.T, api Provider) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	r.Register(api)
+	r.Register(NewFetcherProvider(ProviderDataset, dataset.Sample()))
+	for _, attr := range Attributes {
+		if err := r.Use(attr, ProviderAPI, ProviderDataset); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestEnrichFallsBackToDataset(t *testing.T) {
+	person := &entity.Person{Name: "Olga", Surname: "Ivanova"}
+	state, err := enrichFromProviders(context.Background(), fallbackRegistry(t), person)
+	if err != nil {
+		t.Fatalf("enrichFromProviders: %v", err)
+	}
+	if state.Status() != entity.EnrichmentDone {
+		t.Errorf("status = %s, want %s", state.Status(), entity.EnrichmentDone)
+	}
+	if person.Age == nil || person.Gender == nil || *person.Gender != "female" || person.Nationality == nil || *person.Nationality != "RU" {
+		t.Errorf("person = %+v, want values from the dataset sample", person)
+	}
+	if person.AttributeSources.Gender != entity.SourceEnriched {
+		t.Errorf("gender source = %q, want %q", person.AttributeSources.Gender, entity.SourceEnriched)
+	}
+}
+
+func TestEnrichFallsBackToDatasetWhenAPIHangs(t *testing.T) {
+	person := &entity.Person{Name: "Ivan", Surname: "Petrov"}
+	state, err := enrichFromProviders(context.Background(), fallbackRegistryWith(t, hangingProvider{}), person)
+	if err != nil {
+		t.Fatalf("enrichFromProviders: %v", err)
+	}
+	if state.Status() != entity.EnrichmentDone || person.Age == nil || *person.Age != 36 {
+		t.Errorf("state = %+v, age = %v; want the dataset answer before the deadline", state, person.Age)
+	}
+}
+
+func TestEnrichKeepsErrorWhenFallbackHasNoData(t *testing.T) {
+	person := &entity.Person{Name: "Zebulon", Surname: "Smith"}
+	state, err := enrichFromProviders(context.Background(), fallbackRegistry(t), person)
+	if !errors.Is(err, errUpstreamDown) {
+		t.Fatalf("err = %v, want the upstream error", err)
+	}
+	// Отсутствие имени в справочнике не означает, что данных нет у API.
+	if state.Age != entity.AttributeError || state.Gender != entity.AttributeError || state.Nationality != entity.AttributeError {
+		t.Errorf("state = %+v, want every attribute failed", state)
+	}
+}
+
+func TestEnrichSkipsManualAttributes(t *testing.T) {
+	gender := "male"
+	person := &entity.Person{Name: "Olga", Surname: "Ivanova", Gender: &gender}
+	person.AttributeSources.Gender = entity.SourceManual
+
+	state, err := enrichFromProviders(context.Background(), fallbackRegistry(t), person)
+	if err != nil {
+		t.Fatalf("enrichFromProviders: %v", err)
+	}
+	if *person.Gender != "male" || state.Gender != "" {
+		t.Errorf("manual gender was enriched: gender %s, state %q", *person.Gender, state.Gender)
+	}
+}
+
+// unknownGenderFetcher отвечает на любое имя как genderize без статистики.
+type unknownGenderFetcher struct{}
+
+func (unknownGenderFetcher) FetchAge(context.Context, string) (*client.AgeResult, error) {
+	return nil, nil
+}
+
+func (unknownGenderFetcher) FetchGender(context.Context, string) (*client.GenderResult, error) {
+	return &client.GenderResult{Gender: client.GenderUnknown}, nil
+}
+
+func (unknownGenderFetcher) FetchNationality(context.Context, string) (*client.NationalityResult, error) {
+	return nil, nil
+}
+
+func TestUnknownGenderFallsThroughChain(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewFetcherProvider(ProviderAPI, unknownGenderFetcher{}))
+	r.Register(NewFetcherProvider(ProviderDataset, dataset.Sample()))
+	if err := r.Use(AttributeGender, ProviderAPI, ProviderDataset); err != nil {
+		t.Fatal(err)
+	}
+
+	person := &entity.Person{Name: "Olga", Surname: "Smith"}
+	if _, err := enrichFromProviders(context.Background(), r, person); err != nil {
+		t.Fatalf("enrichFromProviders: %v", err)
+	}
+	if person.Gender == nil || *person.Gender != "female" {
+		t.Errorf("gender = %v, want female from the dataset", person.Gender)
+	}
+
+	person = &entity.Person{Name: "Zebulon", Surname: "Smith"}
+	state, err := enrichFromProviders(context.Background(), r, person)
+	if err != nil {
+		t.Fatalf("enrichFromProviders: %v", err)
+	}
+	if person.Gender != nil || state.Gender != entity.AttributeMissing {
+		t.Errorf("gender = %v, state %q; want no value and state missing", person.Gender, state.Gender)
+	}
+}
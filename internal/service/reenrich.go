@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+// ReenrichPerson заново обогащает одну запись. В асинхронном режиме запись
+// ставится в очередь фонового обогащения и возвращается без изменений.
+func (s *PersonService) ReenrichPerson(id int, async bool) (*entity.Person, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	person, err := s.GetPersonById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if async {
+		if err := enqueueEnrichment(ctx, s.db, id); err != nil {
+			return nil, err
+		}
+		log.Info().Int("id", id).Msg("Re-enrichment queued")
+		return person, nil
+	}
+
+	if err := s.reenrich(ctx, person); err != nil {
+		return nil, err
+	}
+	return s.GetPersonById(id)
+}
+
+// ReenrichPersons ставит в очередь фонового обогащения все записи,
+// подходящие под фильтр. Записи, задача для которых уже выполняется или ждёт
+// выполнения, пропускаются. Возвращает число поставленных задач.
+func (s *PersonService) ReenrichPersons(filter entity.PersonFilter) (int64, error) {
+	if !hasFilterCriteria(filter) {
+		return 0, fmt.Errorf("%w: at least one filter is required", ErrInvalidInput)
+	}
+	filter.IncludeDeleted = false
+
+	selectIDs, args, err := applyPersonFilter(
+		squirrel.Select("id").From("persons").PlaceholderFormat(squirrel.Dollar), filter,
+	).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var queued int64
+	err = s.inTx(ctx, entity.ChangeSourceEnrichment, func(tx *sqlx.Tx) error {
+		if filter.Query != nil {
+			if _, err := tx.ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`,
+				strconv.FormatFloat(s.cfg.SearchSimilarityThreshold, 'f', -1, 64)); err != nil {
+				return err
+			}
+		}
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO enrichment_jobs (person_id)
+			SELECT id FROM (`+selectIDs+`) AS selected
+			ON CONFLICT (person_id) DO UPDATE
+			SET status = 'queued', attempts = 0, last_error = NULL,
+				run_after = NOW(), locked_at = NULL, updated_at = NOW()
+			WHERE enrichment_jobs.status NOT IN ('queued', 'running')
+		`, args...)
+		if err != nil {
+			return err
+		}
+		queued, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to queue re-enrichment")
+		return 0, err
+	}
+
+	log.Info().Int64("queued", queued).Msg("Re-enrichment queued")
+	return queued, nil
+}
+
+func hasFilterCriteria(filter entity.PersonFilter) bool {
+	return filter.Query != nil || filter.Name != nil || filter.Surname != nil ||
+		filter.Patronymic != nil || filter.Gender != nil || filter.Nationality != nil ||
+		filter.MinAge != nil || filter.MaxAge != nil ||
+		filter.EnrichmentStatus != nil || filter.MissingAttributes ||
+		filter.MinGenderProbability != nil || filter.MinNationalityProbability != nil ||
+		filter.MinAgeSampleCount != nil
+}
+
+// reenrich обогащает существующую запись и сохраняет результат. Атрибуты,
+// которые не удалось получить, сохраняют прежние значения.
+func (s *PersonService) reenrich(ctx context.Context, person *entity.Person) error {
+	enriched := enrichmentTarget(person)
+	state, err := enrichFromProviders(ctx, s.providers, enriched)
+	if err := s.applyEnrichment(enriched, state, err); err != nil {
+		return fmt.Errorf("%w: %w", ErrEnrichmentFailed, err)
+	}
+	keepPreviousEnrichment(person, enriched)
+
+	return s.inTx(ctx, entity.ChangeSourceEnrichment, func(tx *sqlx.Tx) error {
+		saved, err := saveEnrichment(ctx, tx, person.ID, enriched)
+		if err == nil && !saved {
+			log.Info().Int("id", person.ID).Msg("Person changed during re-enrichment, result dropped")
+		}
+		return err
+	})
+}
+
+// RunEnrichmentSweeper периодически обогащает заново записи с недостающими
+// атрибутами и записи, обогащённые дольше cfg.EnrichStaleAfter назад, пока
+// ctx не отменён.
+func (s *PersonService) RunEnrichmentSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.SweepEnrichment(ctx)
+			if err != nil {
+				continue
+			}
+			if n > 0 {
+				log.Info().Int("count", n).Msg("Re-enriched persons")
+			}
+		}
+	}
+}
+
+// SweepEnrichment выполняет один проход sweeper'а: берёт до
+// cfg.EnrichSweepBatch кандидатов и обогащает их не более
+// cfg.EnrichSweepConcurrency одновременно и не чаще cfg.EnrichSweepRate в
+// секунду. Возвращает число успешно обогащённых записей.
+//
+// Запись, изменённая менее cfg.EnrichRetryAfter назад, пропускается: так
+// при недоступном API одни и те же записи не запрашиваются на каждом проходе.
+func (s *PersonService) SweepEnrichment(ctx context.Context) (int, error) {
+	// Незаполненным считается только атрибут, обогащение которого включено.
+	missing := ""
+	for _, attr := range s.providers.Enabled() {
+		missing += "OR " + string(attr) + " IS NULL "
+	}
+
+	var persons []entity.Person
+	err := s.db.SelectContext(ctx, &persons, `
+		SELECT * FROM persons p
+		WHERE deleted_at IS NULL
+			AND enrichment_status <> 'pending'
+			AND updated_at < NOW() - make_interval(secs => $1)
+			AND (enrichment_status IN ('partial', 'failed')
+				`+missing+`
+				OR enriched_at IS NULL
+				OR enriched_at < NOW() - make_interval(secs => $2))
+			AND NOT EXISTS (
+				SELECT 1 FROM enrichment_jobs j
+				WHERE j.person_id = p.id AND j.status IN ('queued', 'running')
+			)
+		ORDER BY enriched_at NULLS FIRST, id
+		LIMIT $3
+	`, s.cfg.EnrichRetryAfter.Seconds(), s.cfg.EnrichStaleAfter.Seconds(), s.cfg.EnrichSweepBatch)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to select persons for re-enrichment")
+		return 0, err
+	}
+	if len(persons) == 0 {
+		return 0, nil
+	}
+
+	log.Debug().Int("candidates", len(persons)).Msg("Enrichment sweep started")
+
+	limiter := time.NewTicker(time.Duration(float64(time.Second) / s.cfg.EnrichSweepRate))
+	defer limiter.Stop()
+
+	var (
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, s.cfg.EnrichSweepConcurrency)
+		refreshed atomic.Int64
+	)
+
+dispatch:
+	for i := range persons {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case <-limiter.C:
+		}
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(person *entity.Person) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Начатое обогащение доводится до конца даже при остановке сервиса.
+			personCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			defer cancel()
+
+			if err := s.reenrich(personCtx, person); err != nil {
+				log.Warn().Err(err).Int("id", person.ID).Msg("Re-enrichment failed")
+				return
+			}
+			refreshed.Add(1)
+		}(&persons[i])
+	}
+	wg.Wait()
+
+	return int(refreshed.Load()), nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+// ErrNotDeleted возвращается при попытке восстановить запись, которая не удалена.
+var ErrNotDeleted = errors.New("person is not deleted")
+
+// RestorePerson снимает отметку мягкого удаления.
+func (s *PersonService) RestorePerson(id int) (*entity.Person, error) {
+	log.Debug().
+		Int("id", id).
+		Msg("Restoring person")
+
+	query := `
+		UPDATE persons
+		SET deleted_at = NULL, updated_at = NOW(), version = version + 1
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING *
+	`
+
+	var person entity.Person
+	rows, err := s.db.Queryx(query, id)
+	if err != nil {
+		log.Error().Err(err).Msg("❌ Failed to restore person")
+		return nil, err
+	}
+	found := rows.Next()
+	if found {
+		err = rows.StructScan(&person)
+	}
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan returned values: %w", err)
+	}
+
+	if !found {
+		var exists bool
+		if err := s.db.Get(&exists, `SELECT EXISTS(SELECT 1 FROM persons WHERE id = $1)`, id); err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrNotDeleted
+		}
+		return nil, ErrNotFound
+	}
+
+	log.Info().
+		Int("id", id).
+		Msg("✅ Successfully restored person")
+	return &person, nil
+}
+
+// PurgeDeletedPersons окончательно удаляет записи, мягко удалённые раньше,
+// чем olderThan назад. Если olderThan не задан, используется cfg.PurgeRetention.
+// История удалённых записей сохраняется, а при cfg.PurgeEraseHistory
+// стирается триггером persons_history до строки purge без данных.
+func (s *PersonService) PurgeDeletedPersons(olderThan time.Duration) (int64, error) {
+	if olderThan <= 0 {
+		olderThan = s.cfg.PurgeRetention
+	}
+
+	var n int64
+	err := s.inTx(context.Background(), entity.ChangeSourceAdmin, func(tx *sqlx.Tx) error {
+		if s.cfg.PurgeEraseHistory {
+			if _, err := tx.Exec(`SELECT set_config('person_service.erase_history', 'on', true)`); err != nil {
+				return err
+			}
+		}
+		res, err := tx.Exec(`
+			DELETE FROM persons
+			WHERE deleted_at IS NOT NULL AND deleted_at < NOW() - make_interval(secs => $1)
+		`, olderThan.Seconds())
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("❌ Failed to purge deleted persons")
+		return 0, err
+	}
+
+	log.Info().
+		Int64("count", n).
+		Dur("older_than", olderThan).
+		Bool("history_erased", s.cfg.PurgeEraseHistory).
+		Msg("Purged soft-deleted persons")
+	return n, nil
+}
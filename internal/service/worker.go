@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+
+	"github.com/k1lls3x/person-service/internal/entity"
+)
+
+// maxEnrichBackoff ограничивает паузу между повторными попытками обогащения.
+const maxEnrichBackoff = 5 * time.Minute
+
+type enrichmentJob struct {
+	PersonID int `db:"person_id"`
+	Attempts int `db:"attempts"`
+}
+
+// enqueueEnrichment ставит запись в очередь фонового обогащения. Если задача
+// для записи уже есть, она сбрасывается и запускается заново.
+func enqueueEnrichment(ctx context.Context, db sqlx.ExecerContext, personID int) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO enrichment_jobs (person_id) VALUES ($1)
+		ON CONFLICT (person_id) DO UPDATE
+		SET status = 'queued', attempts = 0, last_error = NULL,
+			run_after = NOW(), locked_at = NULL, updated_at = NOW()
+	`, personID)
+	if err != nil {
+		log.Error().Err(err).Int("id", personID).Msg("Failed to enqueue enrichment")
+	}
+	return err
+}
+
+// RunEnrichmentWorkers запускает workers воркеров фонового обогащения и
+// блокируется до отмены ctx. Состояние задач хранится в enrichment_jobs,
+// поэтому незавершённые задачи подхватываются после перезапуска.
+func (s *PersonService) RunEnrichmentWorkers(ctx context.Context, workers int) {
+	log.Info().Int("workers", workers).Msg("Starting enrichment workers")
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			s.enrichmentWorker(ctx, worker)
+		}(i)
+	}
+	wg.Wait()
+
+	log.Info().Msg("Enrichment workers stopped")
+}
+
+func (s *PersonService) enrichmentWorker(ctx context.Context, worker int) {
+	for {
+		processed, err := s.processNextEnrichmentJob(ctx)
+		if err != nil {
+			log.Error().Err(err).Int("worker", worker).Msg("Enrichment job failed")
+		}
+		if processed {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.cfg.EnrichPollInterval):
+		}
+	}
+}
+
+// claimEnrichmentJob забирает готовую к выполнению задачу. Задачи, зависшие в
+// статусе running дольше EnrichLockTimeout, считаются брошенными.
+func (s *PersonService) claimEnrichmentJob(ctx context.Context) (*enrichmentJob, error) {
+	var job enrichmentJob
+	err := s.db.GetContext(ctx, &job, `
+		UPDATE enrichment_jobs
+		SET status = 'running', locked_at = NOW(), attempts = attempts + 1, updated_at = NOW()
+		WHERE person_id = (
+			SELECT person_id FROM enrichment_jobs
+			WHERE (status = 'queued' AND run_after <= NOW())
+				OR (status = 'running' AND locked_at < NOW() - make_interval(secs => $1))
+			ORDER BY run_after
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING person_id, attempts
+	`, s.cfg.EnrichLockTimeout.Seconds())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// processNextEnrichmentJob выполняет одну задачу из очереди. Возвращает
+// false, если очередь пуста.
+func (s *PersonService) processNextEnrichmentJob(ctx context.Context) (bool, error) {
+	job, err := s.claimEnrichmentJob(ctx)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	// Начатая задача доводится до конца даже при остановке сервиса.
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.cfg.EnrichLockTimeout)
+	defer cancel()
+
+	var person entity.Person
+	err = s.db.GetContext(jobCtx, &person, `SELECT * FROM persons WHERE id = $1 AND deleted_at IS NULL`, job.PersonID)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Info().Int("id", job.PersonID).Msg("Person is gone, dropping enrichment job")
+		_, err = s.db.ExecContext(jobCtx, `
+			UPDATE enrichment_jobs SET status = 'done', locked_at = NULL, updated_at = NOW() WHERE person_id = $1
+		`, job.PersonID)
+		return true, err
+	}
+	if err != nil {
+		return true, err
+	}
+
+	log.Debug().
+		Int("id", person.ID).
+		Int("attempt", job.Attempts).
+		Msg("Processing enrichment job")
+
+	enriched := enrichmentTarget(&person)
+	state, err := enrichFromProviders(jobCtx, s.providers, enriched)
+	if err != nil {
+		// Пока есть попытки, повторяем обогащение целиком. На последней попытке
+		// в режиме partial сохраняем то, что удалось получить.
+		if job.Attempts < s.cfg.EnrichMaxAttempts || state.Status() == entity.EnrichmentFailed {
+			return true, s.failEnrichmentJob(jobCtx, job, err)
+		}
+	}
+	if err := s.applyEnrichment(enriched, state, err); err != nil {
+		return true, s.failEnrichmentJob(jobCtx, job, err)
+	}
+	keepPreviousEnrichment(&person, enriched)
+	return true, s.completeEnrichmentJob(jobCtx, job, enriched)
+}
+
+// saveEnrichment записывает результат обогащения. Условие по ФИО защищает
+// от записи устаревших данных, если его успели поменять, пока шёл запрос к
+// API; в этом случае возвращается false. Атрибуты, которые к моменту записи
+// заданы вручную, не перезаписываются, даже если их задали во время запроса.
+func saveEnrichment(ctx context.Context, tx *sqlx.Tx, id int, enriched *entity.Person) (bool, error) {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE persons
+		SET age = CASE WHEN attribute_sources->>'age' = 'manual' THEN age ELSE $3 END,
+			age_sample_count = CASE WHEN attribute_sources->>'age' = 'manual' THEN age_sample_count ELSE $6 END,
+			gender = CASE WHEN attribute_sources->>'gender' = 'manual' THEN gender ELSE $4 END,
+			gender_probability = CASE WHEN attribute_sources->>'gender' = 'manual' THEN gender_probability ELSE $7 END,
+			nationality = CASE WHEN attribute_sources->>'nationality' = 'manual' THEN nationality ELSE $5 END,
+			nationalities = CASE WHEN attribute_sources->>'nationality' = 'manual' THEN nationalities ELSE $8 END,
+			-- ручные источники из БД побеждают переданные
+			attribute_sources = $13::jsonb || jsonb_strip_nulls(jsonb_build_object(
+				'age', CASE WHEN attribute_sources->>'age' = 'manual' THEN 'manual' END,
+				'gender', CASE WHEN attribute_sources->>'gender' = 'manual' THEN 'manual' END,
+				'nationality', CASE WHEN attribute_sources->>'nationality' = 'manual' THEN 'manual' END
+			)),
+			enrichment_status = $9, enrichment_error = $10, enrichment_state = $11,
+			enriched_at = $12, updated_at = NOW(), version = version + 1
+		WHERE id = $1 AND name = $2 AND surname = $14
+			AND patronymic IS NOT DISTINCT FROM $15 AND deleted_at IS NULL
+	`, id, enriched.Name, enriched.Age, enriched.Gender, enriched.Nationality,
+		enriched.AgeSampleCount, enriched.GenderProbability, enriched.Nationalities,
+		enriched.EnrichmentStatus, enriched.EnrichmentError, enriched.EnrichmentState, enriched.EnrichedAt,
+		enriched.AttributeSources, enriched.Surname, enriched.Patronymic)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *PersonService) completeEnrichmentJob(ctx context.Context, job *enrichmentJob, enriched *entity.Person) error {
+	return s.inTx(ctx, entity.ChangeSourceEnrichment, func(tx *sqlx.Tx) error {
+		if _, err := saveEnrichment(ctx, tx, job.PersonID, enriched); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			UPDATE enrichment_jobs
+			SET status = 'done', last_error = NULL, locked_at = NULL, updated_at = NOW()
+			WHERE person_id = $1
+		`, job.PersonID)
+		if err == nil {
+			log.Info().
+				Int("id", job.PersonID).
+				Str("status", string(enriched.EnrichmentStatus)).
+				Msg("Background enrichment completed")
+		}
+		return err
+	})
+}
+
+// failEnrichmentJob откладывает задачу с экспоненциальной паузой либо, если
+// попытки исчерпаны, помечает запись как failed с текстом ошибки. Запись,
+// которая уже была обогащена раньше (повторное обогащение), сохраняет статус.
+func (s *PersonService) failEnrichmentJob(ctx context.Context, job *enrichmentJob, cause error) error {
+	if job.Attempts < s.cfg.EnrichMaxAttempts {
+		backoff := time.Duration(1<<min(job.Attempts, 16)) * time.Second
+		if backoff > maxEnrichBackoff {
+			backoff = maxEnrichBackoff
+		}
+		// Повторять раньше сброса лимита внешнего API бессмысленно.
+		backoff = max(backoff, RetryAfter(cause))
+		log.Warn().
+			Err(cause).
+			Int("id", job.PersonID).
+			Int("attempt", job.Attempts).
+			Dur("retry_in", backoff).
+			Msg("Background enrichment failed, will retry")
+		_, err := s.db.ExecContext(ctx, `
+			UPDATE enrichment_jobs
+			SET status = 'queued', last_error = $2, locked_at = NULL,
+				run_after = NOW() + make_interval(secs => $3), updated_at = NOW()
+			WHERE person_id = $1
+		`, job.PersonID, cause.Error(), backoff.Seconds())
+		return err
+	}
+
+	log.Error().
+		Err(cause).
+		Int("id", job.PersonID).
+		Int("attempts", job.Attempts).
+		Msg("Background enrichment failed permanently")
+	return s.inTx(ctx, entity.ChangeSourceEnrichment, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE persons
+			SET enrichment_status = CASE WHEN enrichment_status = 'pending' THEN 'failed' ELSE enrichment_status END,
+				enrichment_error = $2,
+				updated_at = NOW(), version = version + 1
+			WHERE id = $1 AND deleted_at IS NULL
+		`, job.PersonID, cause.Error())
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE enrichment_jobs
+			SET status = 'failed', last_error = $2, locked_at = NULL, updated_at = NOW()
+			WHERE person_id = $1
+		`, job.PersonID, cause.Error())
+		return err
+	})
+}
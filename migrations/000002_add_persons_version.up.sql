@@ -0,0 +1 @@
+ALTER TABLE persons ADD COLUMN version INT NOT NULL DEFAULT 1;
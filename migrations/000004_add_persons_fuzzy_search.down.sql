@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_persons_search_translit_trgm;
+DROP INDEX IF EXISTS idx_persons_search_trgm;
+DROP FUNCTION IF EXISTS person_search_text(TEXT, TEXT, TEXT);
+DROP FUNCTION IF EXISTS translit_ru(TEXT);
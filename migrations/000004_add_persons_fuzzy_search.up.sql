@@ -0,0 +1,26 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Транслитерация кириллицы в латиницу, чтобы "Dmitry" находил "Дмитрий" и наоборот.
+CREATE OR REPLACE FUNCTION translit_ru(t TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT translate(
+        replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(
+            lower(t),
+            'щ', 'shch'), 'ш', 'sh'), 'ч', 'ch'), 'ж', 'zh'), 'ю', 'yu'),
+            'я', 'ya'), 'х', 'kh'), 'ц', 'ts'), 'ё', 'e'), 'й', 'y'),
+        'абвгдезиклмнопрстуфыэъь',
+        'abvgdeziklmnoprstufye'
+    )
+$$;
+
+-- Полное ФИО в нижнем регистре; используется и в индексах, и в запросах,
+-- поэтому выражения должны совпадать дословно.
+CREATE OR REPLACE FUNCTION person_search_text(name TEXT, surname TEXT, patronymic TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT lower(name || ' ' || surname || coalesce(' ' || patronymic, ''))
+$$;
+
+CREATE INDEX idx_persons_search_trgm ON persons
+    USING GIN (person_search_text(name, surname, patronymic) gin_trgm_ops);
+CREATE INDEX idx_persons_search_translit_trgm ON persons
+    USING GIN (translit_ru(person_search_text(name, surname, patronymic)) gin_trgm_ops);
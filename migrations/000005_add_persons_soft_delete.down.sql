@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_persons_deleted_at_partial;
+ALTER TABLE persons DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,3 @@
+ALTER TABLE persons ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_persons_deleted_at_partial ON persons(deleted_at) WHERE deleted_at IS NOT NULL;  -- для восстановления и очистки
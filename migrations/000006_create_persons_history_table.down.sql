@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_persons_history ON persons;
+DROP FUNCTION IF EXISTS persons_history_audit();
+DROP TABLE IF EXISTS persons_history;
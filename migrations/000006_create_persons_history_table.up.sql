@@ -0,0 +1,51 @@
+CREATE TABLE persons_history (
+    id BIGSERIAL PRIMARY KEY,
+    person_id INT NOT NULL,  -- без внешнего ключа: история переживает окончательное удаление
+    operation VARCHAR(10) NOT NULL CHECK (operation IN ('insert','update','delete','restore','purge')),
+    source VARCHAR(20) NOT NULL CHECK (source IN ('api','enrichment','import','admin')),
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT clock_timestamp(),
+    old_data JSONB,
+    new_data JSONB
+);
+
+CREATE INDEX idx_persons_history_person_changed_at ON persons_history(person_id, changed_at DESC);
+
+-- Источник изменения передаётся из приложения через
+-- set_config('person_service.change_source', ..., true); по умолчанию 'api'.
+CREATE OR REPLACE FUNCTION persons_history_audit() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+DECLARE
+    op TEXT;
+    src TEXT := coalesce(nullif(current_setting('person_service.change_source', true), ''), 'api');
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO persons_history (person_id, operation, source, new_data)
+        VALUES (NEW.id, 'insert', src, to_jsonb(NEW));
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO persons_history (person_id, operation, source, old_data)
+        VALUES (OLD.id, 'purge', src, to_jsonb(OLD));
+        RETURN NULL;
+    END IF;
+
+    op := CASE
+        WHEN OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN 'delete'
+        WHEN OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN 'restore'
+        ELSE 'update'
+    END;
+    INSERT INTO persons_history (person_id, operation, source, old_data, new_data)
+    VALUES (NEW.id, op, src, to_jsonb(OLD), to_jsonb(NEW));
+    RETURN NULL;
+END
+$$;
+
+CREATE TRIGGER trg_persons_history
+    AFTER INSERT OR UPDATE OR DELETE ON persons
+    FOR EACH ROW EXECUTE FUNCTION persons_history_audit();
+
+-- Существующие записи получают исходную точку истории, чтобы asOf работал и для них.
+INSERT INTO persons_history (person_id, operation, source, changed_at, new_data)
+SELECT id, 'insert', 'import', coalesce(updated_at, created_at, NOW()), to_jsonb(persons)
+FROM persons;
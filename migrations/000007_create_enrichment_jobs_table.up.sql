@@ -0,0 +1,20 @@
+ALTER TABLE persons
+    ADD COLUMN enrichment_status VARCHAR(10) NOT NULL DEFAULT 'done'
+        CHECK (enrichment_status IN ('pending','done','failed')),
+    ADD COLUMN enrichment_error TEXT;
+
+-- Очередь фонового обогащения. Одна задача на человека: повторная постановка
+-- в очередь переиспользует строку.
+CREATE TABLE enrichment_jobs (
+    person_id INT PRIMARY KEY REFERENCES persons(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued','running','done','failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    run_after TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_enrichment_jobs_queued ON enrichment_jobs(run_after) WHERE status = 'queued';
+CREATE INDEX idx_enrichment_jobs_running ON enrichment_jobs(locked_at) WHERE status = 'running';
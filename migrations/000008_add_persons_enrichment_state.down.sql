@@ -0,0 +1,6 @@
+ALTER TABLE persons DROP COLUMN IF EXISTS enrichment_state;
+
+UPDATE persons SET enrichment_status = 'done' WHERE enrichment_status = 'partial';
+ALTER TABLE persons DROP CONSTRAINT IF EXISTS persons_enrichment_status_check;
+ALTER TABLE persons ADD CONSTRAINT persons_enrichment_status_check
+    CHECK (enrichment_status IN ('pending','done','failed'));
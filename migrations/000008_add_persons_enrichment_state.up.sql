@@ -0,0 +1,14 @@
+ALTER TABLE persons DROP CONSTRAINT IF EXISTS persons_enrichment_status_check;
+ALTER TABLE persons ADD CONSTRAINT persons_enrichment_status_check
+    CHECK (enrichment_status IN ('pending','done','partial','failed'));
+
+-- Состояние обогащения по атрибутам: {"age": "ok|missing|error", ...}.
+ALTER TABLE persons ADD COLUMN enrichment_state JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+UPDATE persons
+SET enrichment_state = jsonb_build_object(
+    'age',         CASE WHEN age IS NULL THEN 'missing' ELSE 'ok' END,
+    'gender',      CASE WHEN gender IS NULL THEN 'missing' ELSE 'ok' END,
+    'nationality', CASE WHEN nationality IS NULL THEN 'missing' ELSE 'ok' END
+)
+WHERE enrichment_status = 'done';
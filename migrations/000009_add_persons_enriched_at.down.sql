@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_persons_enriched_at;
+ALTER TABLE persons DROP COLUMN IF EXISTS enriched_at;
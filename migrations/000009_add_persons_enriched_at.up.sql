@@ -0,0 +1,7 @@
+-- Время последнего успешного обогащения. По нему фоновый sweeper находит
+-- устаревшие записи.
+ALTER TABLE persons ADD COLUMN enriched_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE persons SET enriched_at = updated_at WHERE enrichment_status IN ('done','partial');
+
+CREATE INDEX idx_persons_enriched_at ON persons(enriched_at) WHERE deleted_at IS NULL;
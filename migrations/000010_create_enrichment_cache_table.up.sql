@@ -0,0 +1,12 @@
+-- Второй уровень кэша результатов внешних API, общий для всех экземпляров
+-- сервиса. value = NULL — API ответил, что данных по имени нет.
+CREATE TABLE enrichment_cache (
+    attribute VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    value TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (attribute, name)
+);
+
+CREATE INDEX idx_enrichment_cache_expires ON enrichment_cache(expires_at);
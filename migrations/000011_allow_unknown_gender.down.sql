@@ -0,0 +1,5 @@
+UPDATE persons SET gender = NULL WHERE gender = 'unknown';
+ALTER TABLE persons DROP CONSTRAINT IF EXISTS persons_gender_check;
+ALTER TABLE persons ALTER COLUMN gender TYPE VARCHAR(6);
+ALTER TABLE persons ADD CONSTRAINT persons_gender_check
+    CHECK (gender IN ('male','female'));
@@ -0,0 +1,6 @@
+-- genderize возвращает null для имён без статистики — храним это как 'unknown'.
+-- VARCHAR(6) не вмещает 'unknown', поэтому столбец расширяется.
+ALTER TABLE persons ALTER COLUMN gender TYPE VARCHAR(10);
+ALTER TABLE persons DROP CONSTRAINT IF EXISTS persons_gender_check;
+ALTER TABLE persons ADD CONSTRAINT persons_gender_check
+    CHECK (gender IN ('male','female','unknown'));
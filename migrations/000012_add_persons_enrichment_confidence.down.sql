@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_persons_nationality_probability;
+DROP INDEX IF EXISTS idx_persons_gender_probability;
+
+ALTER TABLE persons
+    DROP COLUMN IF EXISTS nationalities,
+    DROP COLUMN IF EXISTS age_sample_count,
+    DROP COLUMN IF EXISTS gender_probability;
+
+DELETE FROM enrichment_cache;
@@ -0,0 +1,14 @@
+-- Достоверность обогащения: вероятность пола, число наблюдений, на которых
+-- основан возраст, и ранжированный список национальностей с вероятностями
+-- вида [{"country_id": "RU", "probability": 0.82}, ...].
+ALTER TABLE persons
+    ADD COLUMN gender_probability DOUBLE PRECISION,
+    ADD COLUMN age_sample_count INT,
+    ADD COLUMN nationalities JSONB;
+
+CREATE INDEX idx_persons_gender_probability ON persons(gender_probability) WHERE deleted_at IS NULL;
+CREATE INDEX idx_persons_nationality_probability
+    ON persons(((nationalities->0->>'probability')::double precision)) WHERE deleted_at IS NULL;
+
+-- Закэшированные ответы хранились без вероятностей.
+DELETE FROM enrichment_cache;
@@ -0,0 +1,11 @@
+-- Источник значения каждого атрибута: {"age": "manual|enriched", ...}.
+-- Значения, заданные вручную (manual), обогащение не перезаписывает.
+ALTER TABLE persons ADD COLUMN attribute_sources JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- Раньше значения задавались только обогащением.
+UPDATE persons
+SET attribute_sources = jsonb_strip_nulls(jsonb_build_object(
+    'age',         CASE WHEN age IS NOT NULL THEN 'enriched' END,
+    'gender',      CASE WHEN gender IS NOT NULL THEN 'enriched' END,
+    'nationality', CASE WHEN nationality IS NOT NULL THEN 'enriched' END
+));
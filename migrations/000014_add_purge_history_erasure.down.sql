@@ -0,0 +1,30 @@
+-- Стёртая при purge история не восстанавливается; возвращается только прежний
+-- триггер.
+CREATE OR REPLACE FUNCTION persons_history_audit() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+DECLARE
+    op TEXT;
+    src TEXT := coalesce(nullif(current_setting('person_service.change_source', true), ''), 'api');
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO persons_history (person_id, operation, source, new_data)
+        VALUES (NEW.id, 'insert', src, to_jsonb(NEW));
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO persons_history (person_id, operation, source, old_data)
+        VALUES (OLD.id, 'purge', src, to_jsonb(OLD));
+        RETURN NULL;
+    END IF;
+
+    op := CASE
+        WHEN OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN 'delete'
+        WHEN OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN 'restore'
+        ELSE 'update'
+    END;
+    INSERT INTO persons_history (person_id, operation, source, old_data, new_data)
+    VALUES (NEW.id, op, src, to_jsonb(OLD), to_jsonb(NEW));
+    RETURN NULL;
+END
+$$;
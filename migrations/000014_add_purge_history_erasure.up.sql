@@ -0,0 +1,38 @@
+-- По умолчанию окончательное удаление (purge) сохраняет историю записи, как
+-- и раньше. Если транзакция задала person_service.erase_history = on
+-- (PURGE_ERASE_HISTORY), журнал записи стирается и от него остаётся только
+-- строка purge без данных — факт удаления, его время и источник.
+CREATE OR REPLACE FUNCTION persons_history_audit() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+DECLARE
+    op TEXT;
+    src TEXT := coalesce(nullif(current_setting('person_service.change_source', true), ''), 'api');
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO persons_history (person_id, operation, source, new_data)
+        VALUES (NEW.id, 'insert', src, to_jsonb(NEW));
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        IF current_setting('person_service.erase_history', true) = 'on' THEN
+            DELETE FROM persons_history WHERE person_id = OLD.id;
+            INSERT INTO persons_history (person_id, operation, source)
+            VALUES (OLD.id, 'purge', src);
+        ELSE
+            INSERT INTO persons_history (person_id, operation, source, old_data)
+            VALUES (OLD.id, 'purge', src, to_jsonb(OLD));
+        END IF;
+        RETURN NULL;
+    END IF;
+
+    op := CASE
+        WHEN OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN 'delete'
+        WHEN OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN 'restore'
+        ELSE 'update'
+    END;
+    INSERT INTO persons_history (person_id, operation, source, old_data, new_data)
+    VALUES (NEW.id, op, src, to_jsonb(OLD), to_jsonb(NEW));
+    RETURN NULL;
+END
+$$;